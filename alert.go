@@ -0,0 +1,165 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana-foundation-sdk/go/alerting"
+	"github.com/grafana/grafana-openapi-client-go/client/provisioning"
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/urfave/cli/v3"
+)
+
+// volatileAlertRuleFields are set by grafana and never part of the code
+var volatileAlertRuleFields = []string{"id", "orgID", "updated", "provenance"}
+
+func (r *Runner) AlertApply(ctx context.Context, c *cli.Command) error {
+	foldername := c.String(CliFolderName)
+	groups, err := r.getAlertRuleGroups(ctx, c)
+	if err != nil {
+		return fmt.Errorf("failed apply alert rules: %w", err)
+	}
+	folderUID, err := r.ensureFolderPath(foldername)
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		_, err := r.client.Provisioning.PutAlertRuleGroup(provisioning.NewPutAlertRuleGroupParamsWithContext(ctx).
+			WithFolderUID(folderUID).
+			WithGroup(g.Title).
+			WithBody(g).
+			WithXDisableProvenance(provenanceHeader(c)))
+		if err != nil {
+			return fmt.Errorf("unable to put alert rule group %s: %w", g.Title, err)
+		}
+		fmt.Printf("%s: %d alert rule(s) applied\n", g.Title, len(g.Rules))
+	}
+	fmt.Println("Alert rules applied")
+	return nil
+}
+
+func (r *Runner) AlertPlan(ctx context.Context, c *cli.Command) error {
+	detailed := c.Bool(CliDetailedExitCode)
+	folderUID := FolderUID(c.String(CliFolderName))
+	groups, err := r.getAlertRuleGroups(ctx, c)
+	if err != nil {
+		return planError(detailed, fmt.Errorf("failed plan alert rules: %w", err))
+	}
+	var result PlanResult
+	for _, g := range groups {
+		change := ResourceChange{Kind: "alert rule group", Uid: folderUID + "/" + g.Title, Title: g.Title}
+		remote, err := r.client.Provisioning.GetAlertRuleGroup(g.Title, folderUID)
+		if err != nil {
+			var notFound *provisioning.GetAlertRuleGroupNotFound
+			if !errors.As(err, &notFound) {
+				return planError(detailed, fmt.Errorf("unable to get alert rule group %s: %w", g.Title, err))
+			}
+			change.Action = PlanCreate
+			result.Changes = append(result.Changes, change)
+			continue
+		}
+		local, err := normalizeRuleGroup(g, nil)
+		if err != nil {
+			return planError(detailed, err)
+		}
+		server, err := normalizeRuleGroup(remote.Payload, g)
+		if err != nil {
+			return planError(detailed, err)
+		}
+		change.Diffs = diffValues("", server, local)
+		change.Action = PlanUnchanged
+		if len(change.Diffs) > 0 {
+			change.Action = PlanUpdate
+		}
+		result.Changes = append(result.Changes, change)
+	}
+	return finishPlan(detailed, result)
+}
+
+func (r *Runner) AlertDestroy(ctx context.Context, c *cli.Command) error {
+	folderUID := FolderUID(c.String(CliFolderName))
+	groups, err := r.getAlertRuleGroups(ctx, c)
+	if err != nil {
+		return fmt.Errorf("failed destroy alert rules: %w", err)
+	}
+	errList := errors.Join(nil)
+	for _, g := range groups {
+		_, err := r.client.Provisioning.DeleteAlertRuleGroup(g.Title, folderUID)
+		if err != nil {
+			var notFound *provisioning.DeleteAlertRuleGroupNotFound
+			if !errors.As(err, &notFound) {
+				errList = errors.Join(errList, fmt.Errorf("unable to delete alert rule group %s: %w", g.Title, err))
+			}
+		}
+	}
+	if errList != nil {
+		return errList
+	}
+	fmt.Println("Destroyed")
+	return nil
+}
+
+// getAlertRuleGroups returns the groups of the AlertRuleCreator converted to provisioning api models
+func (r *Runner) getAlertRuleGroups(ctx context.Context, c *cli.Command) ([]*models.AlertRuleGroup, error) {
+	if r.AlertRules == nil {
+		return nil, fmt.Errorf("no AlertRuleCreator set, use AlertRuleBuilder option")
+	}
+	foldername := c.String(CliFolderName)
+	// grafana keeps alert rules only inside folders, an empty folder uid is rejected with an unclear error
+	if FolderUID(foldername) == "" {
+		return nil, fmt.Errorf("--%s is required, alert rules have to be inside a folder", CliFolderName)
+	}
+	groups, err := r.AlertRules(foldername, c)
+	if err != nil {
+		return nil, fmt.Errorf("failed get alert rules %w", err)
+	}
+	res := make([]*models.AlertRuleGroup, 0, len(groups))
+	for _, g := range groups {
+		p, err := toProvisionedRuleGroup(FolderUID(foldername), g)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+func toProvisionedRuleGroup(folderUID string, g alerting.RuleGroup) (*models.AlertRuleGroup, error) {
+	if g.Title == nil || *g.Title == "" {
+		return nil, fmt.Errorf("alert rule group without title")
+	}
+	g.FolderUid = &folderUID
+	for i := range g.Rules {
+		g.Rules[i].FolderUID = folderUID
+		g.Rules[i].RuleGroup = *g.Title
+	}
+	var res models.AlertRuleGroup
+	if err := convertJSON(g, &res); err != nil {
+		return nil, fmt.Errorf("alert rule group %s: %w", *g.Title, err)
+	}
+	return &res, nil
+}
+
+// normalizeRuleGroup converts a group into its generic json representation and removes all fields grafana manages on its own.
+// If local is set, rule uids generated by grafana are removed for rules the code does not give an uid.
+func normalizeRuleGroup(g *models.AlertRuleGroup, local *models.AlertRuleGroup) (map[string]any, error) {
+	var res map[string]any
+	if err := convertJSON(g, &res); err != nil {
+		return nil, fmt.Errorf("alert rule group %s: %w", g.Title, err)
+	}
+	rules, _ := res["rules"].([]any)
+	for i, rule := range rules {
+		m, ok := rule.(map[string]any)
+		if !ok {
+			continue
+		}
+		for _, f := range volatileAlertRuleFields {
+			delete(m, f)
+		}
+		if local != nil && (i >= len(local.Rules) || local.Rules[i].UID == "") {
+			delete(m, "uid")
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,68 @@
+package grafanasdkclistarter
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+)
+
+func TestNormalizeRuleGroup(t *testing.T) {
+	tests := []struct {
+		name   string
+		server string
+		local  string
+		want   []string
+	}{
+		{
+			name:   "grafana managed fields",
+			server: `{"title":"g","interval":60,"rules":[{"id":7,"orgID":1,"uid":"generated","updated":"2024-01-02T03:04:05Z","provenance":"api","title":"high load","for":"5m"}]}`,
+			local:  `{"title":"g","interval":60,"rules":[{"title":"high load","for":"5m"}]}`,
+		},
+		{
+			name:   "uid of the code",
+			server: `{"title":"g","rules":[{"id":7,"uid":"other","title":"high load"}]}`,
+			local:  `{"title":"g","rules":[{"uid":"load","title":"high load"}]}`,
+			want:   []string{`~ rules[0].uid: "other" => "load"`},
+		},
+		{
+			name:   "edited rule",
+			server: `{"title":"g","rules":[{"id":7,"uid":"generated","provenance":"api","title":"high load","for":"5m"}]}`,
+			local:  `{"title":"g","rules":[{"title":"high load","for":"10m"}]}`,
+			want:   []string{`~ rules[0].for: "5m0s" => "10m0s"`},
+		},
+		{
+			name:   "edited interval",
+			server: `{"title":"g","interval":60,"rules":[]}`,
+			local:  `{"title":"g","interval":120,"rules":[]}`,
+			want:   []string{`~ interval: 60 => 120`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var server, local models.AlertRuleGroup
+			if err := json.Unmarshal([]byte(tt.server), &server); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.local), &local); err != nil {
+				t.Fatal(err)
+			}
+			code, err := normalizeRuleGroup(&local, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			remote, err := normalizeRuleGroup(&server, &local)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, d := range diffValues("", remote, code) {
+				got = append(got, d.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diff = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package grafanasdkclistarter
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/urfave/cli/v3"
+)
+
+const (
+	// backfillDir keeps the openmetrics files inside the prometheus container
+	backfillDir = "/backfill"
+	// backfillMarker is written into the tsdb after the backfill, restarts of the container and persisted data keep the history once
+	backfillMarker = "/prometheus/.backfilled"
+	// backfillScript creates tsdb blocks of all openmetrics files before prometheus starts, the prometheus args follow the script
+	backfillScript = `set -e
+if [ -e ` + backfillMarker + ` ]; then
+  echo "backfill skipped, the data dir already has the history"
+else
+  for f in ` + backfillDir + `/*.om; do
+    [ -e "$f" ] || continue
+    echo "backfill $f"
+    promtool tsdb create-blocks-from openmetrics "$f" /prometheus
+  done
+  touch ` + backfillMarker + `
+fi
+exec /bin/prometheus "$@"`
+	// prometheusRetention is the default tsdb retention of prometheus, the dev stack does not change it
+	prometheusRetention = 15 * 24 * time.Hour
+)
+
+// BackfillSeries is a series of the dev prometheus history generated in go
+type BackfillSeries struct {
+	Name string
+	Help string
+	// Type is gauge or counter, counters get the _total suffix
+	Type   string
+	Labels map[string]string
+	// Value returns the sample at t. Values of counters have to increase.
+	Value func(t time.Time) float64
+}
+
+// DevBackfill adds series whose history is written into the dev prometheus before it starts
+func DevBackfill(series ...BackfillSeries) Option {
+	return func(runner *Runner, app *cli.Command) error {
+		for _, s := range series {
+			if s.Name == "" || s.Value == nil {
+				return fmt.Errorf("backfill series needs a name and a value function")
+			}
+			if s.Type != "" && s.Type != "gauge" && s.Type != "counter" {
+				return fmt.Errorf("backfill series %s: type %s is not supported", s.Name, s.Type)
+			}
+		}
+		runner.devBackfill = append(runner.devBackfill, series...)
+		return nil
+	}
+}
+
+// backfillFiles returns the openmetrics files to backfill, the file of the flag and the generated one of the DevBackfill options
+func (r *Runner) backfillFiles(c *cli.Command, now time.Time) ([]testcontainers.ContainerFile, error) {
+	var res []testcontainers.ContainerFile
+	if file := c.String(CliDevBackfillFile); file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read backfill file: %w", err)
+		}
+		if !bytes.Contains(b, []byte("# EOF")) {
+			return nil, fmt.Errorf("backfill file %s is no openmetrics file, it has to end with # EOF", file)
+		}
+		res = append(res, testcontainers.ContainerFile{Reader: bytes.NewReader(b), ContainerFilePath: backfillDir + "/file.om", FileMode: 0644})
+	}
+	if len(r.devBackfill) > 0 {
+		rng, step := c.Duration(CliDevBackfillRange), c.Duration(CliDevBackfillStep)
+		if rng <= 0 || step <= 0 {
+			return nil, fmt.Errorf("--%s and --%s have to be positive", CliDevBackfillRange, CliDevBackfillStep)
+		}
+		if rng > prometheusRetention {
+			fmt.Printf("warning: --%s %s is longer than the prometheus retention of %s, older samples are deleted\n", CliDevBackfillRange, rng, prometheusRetention)
+		}
+		b := renderOpenMetrics(r.devBackfill, now.Add(-rng), now, step)
+		res = append(res, testcontainers.ContainerFile{Reader: bytes.NewReader(b), ContainerFilePath: backfillDir + "/generated.om", FileMode: 0644})
+	}
+	return res, nil
+}
+
+// renderOpenMetrics writes the samples of all series from start to end. Series of the same metric are grouped.
+func renderOpenMetrics(series []BackfillSeries, start, end time.Time, step time.Duration) []byte {
+	families := map[string][]BackfillSeries{}
+	var order []string
+	for _, s := range series {
+		name := s.Name
+		if s.Type == "counter" {
+			name = strings.TrimSuffix(name, "_total")
+		}
+		if _, ok := families[name]; !ok {
+			order = append(order, name)
+		}
+		families[name] = append(families[name], s)
+	}
+	var buf bytes.Buffer
+	for _, name := range order {
+		list := families[name]
+		metricType, sample := "gauge", name
+		if list[0].Type == "counter" {
+			metricType, sample = "counter", name+"_total"
+		}
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", name, metricType)
+		if list[0].Help != "" {
+			fmt.Fprintf(&buf, "# HELP %s %s\n", name, list[0].Help)
+		}
+		for _, s := range list {
+			labels := openMetricsLabels(s.Labels)
+			for t := start; !t.After(end); t = t.Add(step) {
+				fmt.Fprintf(&buf, "%s%s %s %d\n", sample, labels, strconv.FormatFloat(s.Value(t), 'g', -1, 64), t.Unix())
+			}
+		}
+	}
+	buf.WriteString("# EOF\n")
+	return buf.Bytes()
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func openMetricsLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := sortedKeys(labels)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=\"%s\"", k, labelValueEscaper.Replace(labels[k])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// withBackfill runs the backfill script as entrypoint of the prometheus container, the cmd of the request are the prometheus args
+func withBackfill(req *testcontainers.ContainerRequest, files []testcontainers.ContainerFile) {
+	req.Files = append(req.Files, files...)
+	req.Entrypoint = []string{"/bin/sh", "-c", backfillScript, "prometheus"}
+}
@@ -0,0 +1,175 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+func TestRenderOpenMetrics(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	elapsed := func(t time.Time) float64 { return t.Sub(start).Seconds() }
+	tests := []struct {
+		name   string
+		series []BackfillSeries
+		end    time.Time
+		step   time.Duration
+		want   string
+	}{
+		{
+			name: "no series",
+			end:  start,
+			step: time.Minute,
+			want: "# EOF\n",
+		},
+		{
+			name:   "gauge",
+			series: []BackfillSeries{{Name: "temperature", Help: "room temperature", Value: func(time.Time) float64 { return 21.5 }}},
+			end:    start.Add(2 * time.Minute),
+			step:   time.Minute,
+			want: `# TYPE temperature gauge
+# HELP temperature room temperature
+temperature 21.5 1700000000
+temperature 21.5 1700000060
+temperature 21.5 1700000120
+# EOF
+`,
+		},
+		{
+			name: "counters of the same family",
+			series: []BackfillSeries{
+				{Name: "http_requests_total", Type: "counter", Labels: map[string]string{"status": "200", "path": "/"}, Value: elapsed},
+				{Name: "http_requests", Type: "counter", Labels: map[string]string{"status": "500", "path": "/"}, Value: elapsed},
+			},
+			end:  start.Add(30 * time.Second),
+			step: 30 * time.Second,
+			want: `# TYPE http_requests counter
+http_requests_total{path="/",status="200"} 0 1700000000
+http_requests_total{path="/",status="200"} 30 1700000030
+http_requests_total{path="/",status="500"} 0 1700000000
+http_requests_total{path="/",status="500"} 30 1700000030
+# EOF
+`,
+		},
+		{
+			name:   "escaped label values",
+			series: []BackfillSeries{{Name: "info", Labels: map[string]string{"msg": "say \"hi\"\n\\"}, Value: func(time.Time) float64 { return 1 }}},
+			end:    start.Add(time.Second),
+			step:   time.Minute,
+			want: `# TYPE info gauge
+info{msg="say \"hi\"\n\\"} 1 1700000000
+# EOF
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(renderOpenMetrics(tt.series, start, tt.end, tt.step))
+			if got != tt.want {
+				t.Errorf("renderOpenMetrics() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackfillScriptRunsOnce(t *testing.T) {
+	dir := t.TempDir()
+	for _, d := range []string{"backfill", "prometheus", "bin"} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "backfill", "file.om"), []byte("# EOF\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	calls := filepath.Join(dir, "calls")
+	promtool := "#!/bin/sh\necho \"$@\" >> " + calls + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "bin", "promtool"), []byte(promtool), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// the script runs outside of the container, prometheus is not started
+	script := strings.NewReplacer(
+		`exec /bin/prometheus "$@"`, "true",
+		backfillDir, filepath.Join(dir, "backfill"),
+		"/prometheus", filepath.Join(dir, "prometheus"),
+	).Replace(backfillScript)
+
+	for i := range 2 {
+		cmd := exec.Command("/bin/sh", "-c", script, "prometheus")
+		cmd.Env = append(os.Environ(), "PATH="+filepath.Join(dir, "bin")+":"+os.Getenv("PATH"))
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("run %d: %v\n%s", i, err, out)
+		}
+	}
+	b, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(b), "create-blocks-from"); n != 1 {
+		t.Errorf("promtool ran %d times for two starts, want 1:\n%s", n, b)
+	}
+}
+
+func TestBackfillFilesWarnsAboutRetention(t *testing.T) {
+	tests := []struct {
+		name     string
+		rng      string
+		wantWarn bool
+	}{
+		{name: "default range", rng: "168h"},
+		{name: "retention", rng: "360h"},
+		{name: "longer than retention", rng: "720h", wantWarn: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Runner{devBackfill: []BackfillSeries{{Name: "up", Value: func(time.Time) float64 { return 1 }}}}
+			cmd := &cli.Command{
+				Name: "run",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: CliDevBackfillFile},
+					&cli.DurationFlag{Name: CliDevBackfillRange},
+					&cli.DurationFlag{Name: CliDevBackfillStep, Value: time.Hour},
+				},
+				Action: func(ctx context.Context, c *cli.Command) error {
+					_, err := r.backfillFiles(c, time.Unix(1700000000, 0))
+					return err
+				},
+			}
+			out := captureStdout(t, func() {
+				if err := cmd.Run(context.Background(), []string{"run", "--" + CliDevBackfillRange, tt.rng}); err != nil {
+					t.Fatal(err)
+				}
+			})
+			if got := strings.Contains(out, "warning: --"+CliDevBackfillRange); got != tt.wantWarn {
+				t.Errorf("warning = %t, want %t, output: %q", got, tt.wantWarn, out)
+			}
+		})
+	}
+}
+
+// captureStdout returns everything f prints
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	done := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		done <- string(b)
+	}()
+	f()
+	w.Close()
+	return <-done
+}
@@ -4,12 +4,32 @@ import (
 	"fmt"
 	"slices"
 
+	"github.com/grafana/grafana-foundation-sdk/go/alerting"
 	"github.com/grafana/grafana-foundation-sdk/go/dashboard"
 	"github.com/urfave/cli/v3"
 )
 
 type DashboardCreator func(folderName string, c *cli.Command) ([]dashboard.Dashboard, error)
 
+// FolderDashboardCreator places every dashboard into its own (nested) folder below the foldername flag
+type FolderDashboardCreator func(folderName string, c *cli.Command) ([]FolderDashboard, error)
+
+type LibraryPanelCreator func(folderName string, c *cli.Command) ([]LibraryPanel, error)
+
+type PermissionCreator func(folderName string, c *cli.Command) (Permissions, error)
+
+type DatasourceCreator func(c *cli.Command) ([]Datasource, error)
+
+type AlertRuleCreator func(folderName string, c *cli.Command) ([]alerting.RuleGroup, error)
+
+type ContactPointCreator func(c *cli.Command) ([]alerting.ContactPoint, error)
+
+type NotificationPolicyCreator func(c *cli.Command) (alerting.NotificationPolicy, error)
+
+type MuteTimingCreator func(c *cli.Command) ([]alerting.MuteTiming, error)
+
+type NotificationTemplateCreator func(c *cli.Command) ([]alerting.NotificationTemplate, error)
+
 func DashboardBuilder(d DashboardCreator) Option {
 	return func(runner *Runner, app *cli.Command) error {
 		if runner.Dashboard != nil {
@@ -20,10 +40,108 @@ func DashboardBuilder(d DashboardCreator) Option {
 	}
 }
 
+func FolderDashboardBuilder(d FolderDashboardCreator) Option {
+	return func(runner *Runner, app *cli.Command) error {
+		if runner.FolderDashboards != nil {
+			return fmt.Errorf("FolderDashboards already set")
+		}
+		runner.FolderDashboards = d
+		return nil
+	}
+}
+
+func LibraryPanelBuilder(l LibraryPanelCreator) Option {
+	return func(runner *Runner, app *cli.Command) error {
+		if runner.LibraryPanels != nil {
+			return fmt.Errorf("LibraryPanels already set")
+		}
+		runner.LibraryPanels = l
+		return nil
+	}
+}
+
+func PermissionBuilder(p PermissionCreator) Option {
+	return func(runner *Runner, app *cli.Command) error {
+		if runner.Permissions != nil {
+			return fmt.Errorf("Permissions already set")
+		}
+		runner.Permissions = p
+		return nil
+	}
+}
+
+func DatasourceBuilder(d DatasourceCreator) Option {
+	return func(runner *Runner, app *cli.Command) error {
+		if runner.Datasources != nil {
+			return fmt.Errorf("Datasources already set")
+		}
+		runner.Datasources = d
+		return nil
+	}
+}
+
+func AlertRuleBuilder(a AlertRuleCreator) Option {
+	return func(runner *Runner, app *cli.Command) error {
+		if runner.AlertRules != nil {
+			return fmt.Errorf("AlertRules already set")
+		}
+		runner.AlertRules = a
+		return nil
+	}
+}
+
+func ContactPointBuilder(cp ContactPointCreator) Option {
+	return func(runner *Runner, app *cli.Command) error {
+		if runner.ContactPoints != nil {
+			return fmt.Errorf("ContactPoints already set")
+		}
+		runner.ContactPoints = cp
+		return nil
+	}
+}
+
+func NotificationPolicyBuilder(p NotificationPolicyCreator) Option {
+	return func(runner *Runner, app *cli.Command) error {
+		if runner.NotificationPolicy != nil {
+			return fmt.Errorf("NotificationPolicy already set")
+		}
+		runner.NotificationPolicy = p
+		return nil
+	}
+}
+
+func MuteTimingBuilder(m MuteTimingCreator) Option {
+	return func(runner *Runner, app *cli.Command) error {
+		if runner.MuteTimings != nil {
+			return fmt.Errorf("MuteTimings already set")
+		}
+		runner.MuteTimings = m
+		return nil
+	}
+}
+
+func NotificationTemplateBuilder(t NotificationTemplateCreator) Option {
+	return func(runner *Runner, app *cli.Command) error {
+		if runner.NotificationTemplates != nil {
+			return fmt.Errorf("NotificationTemplates already set")
+		}
+		runner.NotificationTemplates = t
+		return nil
+	}
+}
+
 func DefaultDashboardCliFlagValue(key CliValues, value string) Option {
+	return defaultCliFlagValue("dashboard", key, value)
+}
+
+func DefaultAlertCliFlagValue(key CliValues, value string) Option {
+	return defaultCliFlagValue("alert", key, value)
+}
+
+func defaultCliFlagValue(command string, key CliValues, value string) Option {
 	return func(runner *Runner, app *cli.Command) error {
 		for _, c := range app.Commands {
-			if c.Name == "dashboard" {
+			if c.Name == command {
 				for _, f := range c.Flags {
 					if slices.Contains(f.Names(), key) {
 						strFlag, ok := f.(*cli.StringFlag)
@@ -0,0 +1,509 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/urfave/cli/v3"
+)
+
+// Kinds of problems found by dashboard check
+const (
+	CheckParseError    = "parse error"
+	CheckQueryError    = "query error"
+	CheckEmptyResult   = "empty result"
+	CheckUnknownMetric = "unknown metric"
+	CheckNoSample      = "no sample value"
+)
+
+// CheckExitCodeProblems is the exit code of dashboard check if a query has a problem
+const CheckExitCodeProblems = 1
+
+// checkWarnings are the kinds of issues which do not fail dashboard check, a variable without sample only means
+// there is no data for it yet
+var checkWarnings = map[string]bool{CheckNoSample: true}
+
+// checkBuiltinVariables are the sample values of the global grafana variables
+var checkBuiltinVariables = map[string]string{
+	"__rate_interval": "1m",
+	"__interval":      "1m",
+	"__interval_ms":   "60000",
+	"__range":         "1h",
+	"__range_s":       "3600",
+	"__range_ms":      "3600000",
+}
+
+// checkVariablePattern matches $var, ${var}, ${var:format} and [[var]]
+var checkVariablePattern = regexp.MustCompile(`\$\{(\w+)(?::[^}]*)?\}|\[\[(\w+)(?::[^\]]*)?\]\]|\$(\w+)`)
+
+// checkEqualMatcher matches the label matchers label="value" and label!="value"
+var checkEqualMatcher = regexp.MustCompile(`(\w+\s*)(!?=)(\s*"(?:[^"\\]|\\.)*")`)
+
+// checkVariableFunction matches the grafana functions of prometheus variable queries like label_values(up, job)
+var checkVariableFunction = regexp.MustCompile(`(?s)^\s*(label_values|label_names|metrics|query_result)\s*\((.*)\)\s*$`)
+
+// CheckIssue is a problem of a query found by dashboard check
+type CheckIssue struct {
+	Dashboard string
+	// Panel is the panel title or "variable <name>"
+	Panel   string
+	RefId   string
+	Kind    string
+	Message string
+}
+
+// Check runs every prometheus query of the dashboards against a prometheus and reports parse errors, empty results and unknown metrics
+func (r *Runner) Check(ctx context.Context, c *cli.Command) error {
+	promUrl := c.String(CliCheckPrometheusUrl)
+	if promUrl == "" {
+		state, err := readDevState()
+		if err != nil {
+			return fmt.Errorf("no prometheus given, use --%s or a detached dev stack: %w", CliCheckPrometheusUrl, err)
+		}
+		promUrl = state.PrometheusUrl
+	}
+	samples := map[string]string{}
+	for _, v := range c.StringSlice(CliCheckVar) {
+		k, value, ok := strings.Cut(v, "=")
+		if !ok || k == "" {
+			return fmt.Errorf("--%s: %q is not a name=value pair", CliCheckVar, v)
+		}
+		samples[k] = value
+	}
+	dashboards, err := r.getDashboards(ctx, c)
+	if err != nil {
+		return fmt.Errorf("failed check: %w", err)
+	}
+	prom := checkPrometheus{url: strings.TrimSuffix(promUrl, "/"), client: &http.Client{Timeout: 30 * time.Second}, now: time.Now()}
+	var metrics map[string]bool
+	names, err := prom.labelValues(ctx, "__name__", "")
+	if err != nil {
+		fmt.Printf("unable to get the metric names, unknown metrics are not reported: %s\n", err)
+	} else {
+		metrics = map[string]bool{}
+		for _, n := range names {
+			metrics[n] = true
+		}
+	}
+
+	var issues []CheckIssue
+	queries := 0
+	for _, fd := range dashboards {
+		body, err := normalizeDashboard(fd.Dashboard)
+		if err != nil {
+			return err
+		}
+		title, _ := body["title"].(string)
+		check := dashboardCheck{prom: prom, metrics: metrics, dashboard: title, values: map[string]string{}, patterns: map[string]bool{}}
+		check.variables(ctx, body, samples)
+		check.panels(ctx, body)
+		issues = append(issues, check.issues...)
+		queries += check.queries
+	}
+	printCheck(issues)
+	problems := countCheckProblems(issues)
+	fmt.Printf("%d query(s) checked against %s, %d problem(s), %d warning(s)\n", queries, prom.url, problems, len(issues)-problems)
+	if problems > 0 {
+		return cli.Exit("", CheckExitCodeProblems)
+	}
+	return nil
+}
+
+// countCheckProblems returns the number of issues which are no warning
+func countCheckProblems(issues []CheckIssue) int {
+	res := 0
+	for _, is := range issues {
+		if !checkWarnings[is.Kind] {
+			res++
+		}
+	}
+	return res
+}
+
+func printCheck(issues []CheckIssue) {
+	dashboard := ""
+	for i, is := range issues {
+		if i == 0 || is.Dashboard != dashboard {
+			dashboard = is.Dashboard
+			fmt.Println(dashboard)
+		}
+		ref := ""
+		if is.RefId != "" {
+			ref = " [" + is.RefId + "]"
+		}
+		kind := is.Kind
+		if checkWarnings[kind] {
+			kind = "warning: " + kind
+		}
+		fmt.Printf("  %s%s: %s: %s\n", is.Panel, ref, kind, is.Message)
+	}
+}
+
+// dashboardCheck collects the issues of one normalized dashboard
+type dashboardCheck struct {
+	prom      checkPrometheus
+	metrics   map[string]bool
+	dashboard string
+	// values are the sample values of the dashboard variables
+	values map[string]string
+	// patterns are the variables without sample, their value .* is a regex
+	patterns map[string]bool
+	issues   []CheckIssue
+	queries  int
+}
+
+func (d *dashboardCheck) report(panel, refId, kind, message string) {
+	d.issues = append(d.issues, CheckIssue{Dashboard: d.dashboard, Panel: panel, RefId: refId, Kind: kind, Message: message})
+}
+
+// variables checks the prometheus queries of the variables and resolves a sample value of every variable in order, so chained variables see the values before
+func (d *dashboardCheck) variables(ctx context.Context, body map[string]any, samples map[string]string) {
+	templating, _ := body["templating"].(map[string]any)
+	list, _ := templating["list"].([]any)
+	for _, v := range list {
+		variable, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := variable["name"].(string)
+		varType, _ := variable["type"].(string)
+		if name == "" || varType == "adhoc" {
+			continue
+		}
+		panel := "variable " + name
+		var query string
+		switch q := variable["query"].(type) {
+		case string:
+			query = q
+		case map[string]any:
+			query, _ = q["query"].(string)
+		}
+		sample, ok := samples[name]
+		if !ok {
+			sample, ok = currentValue(variable)
+		}
+		if varType == "query" && isPrometheusRef(variable["datasource"]) && query != "" {
+			resolved := d.variableQuery(ctx, panel, d.substitute(query))
+			if !ok && resolved != "" {
+				sample, ok = resolved, true
+			}
+		}
+		if !ok && (varType == "custom" || varType == "interval") {
+			sample, ok = firstCustomValue(query)
+		}
+		if !ok && (varType == "constant" || varType == "textbox") {
+			sample, ok = query, query != ""
+		}
+		if !ok {
+			d.report(panel, "", CheckNoSample, fmt.Sprintf("no value found, use --%s %s=value, .* is used", CliCheckVar, name))
+			sample = ".*"
+			d.patterns[name] = true
+		}
+		d.values[name] = sample
+	}
+}
+
+// variableQuery checks a variable query and returns a sample value of it
+func (d *dashboardCheck) variableQuery(ctx context.Context, panel, query string) string {
+	m := checkVariableFunction.FindStringSubmatch(query)
+	if m == nil {
+		d.query(ctx, panel, "", query)
+		return ""
+	}
+	args := strings.TrimSpace(m[2])
+	switch m[1] {
+	case "label_values":
+		expr, label := "", args
+		if i := strings.LastIndex(args, ","); i >= 0 {
+			expr, label = strings.TrimSpace(args[:i]), strings.TrimSpace(args[i+1:])
+			if !d.query(ctx, panel, "", expr) {
+				return ""
+			}
+		}
+		values, err := d.prom.labelValues(ctx, label, expr)
+		if err != nil {
+			d.report(panel, "", CheckQueryError, err.Error())
+			return ""
+		}
+		// without values the variable has no sample, which is a warning only
+		if len(values) == 0 {
+			return ""
+		}
+		return values[0]
+	case "label_names":
+		var names []string
+		if err := d.prom.get(ctx, "/api/v1/labels", url.Values{}, &names); err != nil {
+			d.report(panel, "", CheckQueryError, err.Error())
+			return ""
+		}
+		if len(names) > 0 {
+			return names[0]
+		}
+	case "metrics":
+		re, err := regexp.Compile(strings.Trim(args, `"'`))
+		if err != nil {
+			d.report(panel, "", CheckParseError, err.Error())
+			return ""
+		}
+		for _, name := range sortedKeys(d.metrics) {
+			if re.MatchString(name) {
+				return name
+			}
+		}
+	case "query_result":
+		d.query(ctx, panel, "", args)
+	}
+	return ""
+}
+
+// panels checks the prometheus targets of all panels, also the ones inside collapsed rows
+func (d *dashboardCheck) panels(ctx context.Context, body map[string]any) {
+	panels, _ := body["panels"].([]any)
+	for _, p := range panels {
+		panel, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+		if nested, ok := panel["panels"].([]any); ok {
+			d.panels(ctx, map[string]any{"panels": nested})
+		}
+		targets, _ := panel["targets"].([]any)
+		if len(targets) == 0 || !isPrometheusRef(panel["datasource"]) {
+			continue
+		}
+		title, _ := panel["title"].(string)
+		if title == "" {
+			title = fmt.Sprintf("panel %v", panel["id"])
+		}
+		for _, t := range targets {
+			target, ok := t.(map[string]any)
+			if !ok || (target["datasource"] != nil && !isPrometheusRef(target["datasource"])) {
+				continue
+			}
+			expr, _ := target["expr"].(string)
+			if strings.TrimSpace(expr) == "" {
+				continue
+			}
+			refId, _ := target["refId"].(string)
+			d.query(ctx, title, refId, d.substitute(expr))
+		}
+	}
+}
+
+// query runs the expression and reports its problems, it returns false if the expression fails
+func (d *dashboardCheck) query(ctx context.Context, panel, refId, expr string) bool {
+	d.queries++
+	names, err := promqlMetricNames(expr)
+	if err != nil {
+		d.report(panel, refId, CheckParseError, err.Error())
+		return false
+	}
+	empty, err := d.prom.query(ctx, expr)
+	if err != nil {
+		kind := CheckQueryError
+		if err, ok := err.(checkPrometheusError); ok && err.errorType == "bad_data" {
+			kind = CheckParseError
+		}
+		d.report(panel, refId, kind, err.Error())
+		return false
+	}
+	unknown := false
+	if d.metrics != nil {
+		for _, name := range names {
+			if !d.metrics[name] {
+				d.report(panel, refId, CheckUnknownMetric, name)
+				unknown = true
+			}
+		}
+	}
+	if empty && !unknown {
+		d.report(panel, refId, CheckEmptyResult, expr)
+	}
+	return true
+}
+
+// substitute replaces the variables by their sample values, unknown variables are kept.
+// Equal matchers of variables without sample become regex matchers, so .* matches every value.
+func (d *dashboardCheck) substitute(expr string) string {
+	expr = checkEqualMatcher.ReplaceAllStringFunc(expr, func(s string) string {
+		m := checkEqualMatcher.FindStringSubmatch(s)
+		for _, v := range checkVariablePattern.FindAllStringSubmatch(m[3], -1) {
+			if d.patterns[v[1]+v[2]+v[3]] {
+				op := "=~"
+				if m[2] == "!=" {
+					op = "!~"
+				}
+				return m[1] + op + m[3]
+			}
+		}
+		return s
+	})
+	return checkVariablePattern.ReplaceAllStringFunc(expr, func(s string) string {
+		m := checkVariablePattern.FindStringSubmatch(s)
+		name := m[1] + m[2] + m[3]
+		if v, ok := d.values[name]; ok {
+			return v
+		}
+		if v, ok := checkBuiltinVariables[name]; ok {
+			return v
+		}
+		return s
+	})
+}
+
+// currentValue returns the selected value of a variable, all is no sample
+func currentValue(variable map[string]any) (string, bool) {
+	current, _ := variable["current"].(map[string]any)
+	var value string
+	switch v := current["value"].(type) {
+	case string:
+		value = v
+	case []any:
+		if len(v) > 0 {
+			value, _ = v[0].(string)
+		}
+	}
+	if value == "" || value == "$__all" {
+		return "", false
+	}
+	return value, true
+}
+
+// firstCustomValue returns the first value of a custom variable query like "a,b" or "key : value"
+func firstCustomValue(query string) (string, bool) {
+	first, _, _ := strings.Cut(query, ",")
+	if _, value, ok := strings.Cut(first, " : "); ok {
+		first = value
+	}
+	first = strings.TrimSpace(first)
+	return first, first != ""
+}
+
+// checkPrometheus runs queries by the http api of prometheus
+type checkPrometheus struct {
+	url    string
+	client *http.Client
+	now    time.Time
+}
+
+type checkPrometheusError struct {
+	errorType string
+	message   string
+}
+
+func (e checkPrometheusError) Error() string {
+	return e.message
+}
+
+func (p checkPrometheus) get(ctx context.Context, path string, params url.Values, data any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to reach prometheus: %w", err)
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	var body struct {
+		Status    string          `json:"status"`
+		Data      json.RawMessage `json:"data"`
+		ErrorType string          `json:"errorType"`
+		Error     string          `json:"error"`
+	}
+	if err := json.Unmarshal(b, &body); err != nil {
+		return fmt.Errorf("unexpected answer of prometheus (%s): %w", res.Status, err)
+	}
+	if body.Status != "success" {
+		return checkPrometheusError{errorType: body.ErrorType, message: body.Error}
+	}
+	return json.Unmarshal(body.Data, data)
+}
+
+// query runs an instant query and returns if its result is empty
+func (p checkPrometheus) query(ctx context.Context, expr string) (bool, error) {
+	var data struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	}
+	params := url.Values{"query": {expr}, "time": {strconv.FormatInt(p.now.Unix(), 10)}}
+	if err := p.get(ctx, "/api/v1/query", params, &data); err != nil {
+		return false, err
+	}
+	if data.ResultType != "vector" && data.ResultType != "matrix" {
+		return false, nil
+	}
+	var result []json.RawMessage
+	if err := json.Unmarshal(data.Result, &result); err != nil {
+		return false, err
+	}
+	return len(result) == 0, nil
+}
+
+// labelValues returns the values of the label, match limits them to the series of a selector
+func (p checkPrometheus) labelValues(ctx context.Context, label, match string) ([]string, error) {
+	params := url.Values{}
+	if match != "" {
+		params.Set("match[]", match)
+	}
+	var values []string
+	err := p.get(ctx, "/api/v1/label/"+url.PathEscape(label)+"/values", params, &values)
+	return values, err
+}
+
+// checkFunctions are the promql functions with the experimental ones enabled. Experimental functions are a setting of
+// the prometheus the queries run at, so they are no parse error. The global parser.EnableExperimentalFunctions is left
+// untouched for other users of the parser, the experimental aggregations limitk and limit_ratio are only known with it.
+var checkFunctions = func() map[string]*parser.Function {
+	res := make(map[string]*parser.Function, len(parser.Functions))
+	for name, f := range parser.Functions {
+		enabled := *f
+		enabled.Experimental = false
+		res[name] = &enabled
+	}
+	return res
+}()
+
+// promqlMetricNames parses the expression and returns the metric names of its selectors
+func promqlMetricNames(expr string) ([]string, error) {
+	p := parser.NewParser(expr, parser.WithFunctions(checkFunctions))
+	defer p.Close()
+	parsed, err := p.ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	seen := map[string]bool{}
+	parser.Inspect(parsed, func(node parser.Node, _ []parser.Node) error {
+		selector, ok := node.(*parser.VectorSelector)
+		if !ok {
+			return nil
+		}
+		name := selector.Name
+		for _, m := range selector.LabelMatchers {
+			if name == "" && m.Name == labels.MetricName && m.Type == labels.MatchEqual {
+				name = m.Value
+			}
+		}
+		if name != "" && !seen[name] {
+			seen[name] = true
+			res = append(res, name)
+		}
+		return nil
+	})
+	return res, nil
+}
@@ -0,0 +1,147 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fasibio/grafanaSdkCliStarter/query"
+	"github.com/grafana/grafana-foundation-sdk/go/cog"
+	"github.com/grafana/grafana-foundation-sdk/go/dashboard"
+	"github.com/grafana/grafana-foundation-sdk/go/timeseries"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/urfave/cli/v3"
+)
+
+func TestPromqlMetricNames(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    []string
+		wantErr bool
+	}{
+		{expr: `up`, want: []string{"up"}},
+		{expr: `sum(rate(http_requests_total{job="api", path=~"/a|/b"}[5m])) by (job)`, want: []string{"http_requests_total"}},
+		{expr: `sum by (job) (rate(http_requests_total[5m]))`, want: []string{"http_requests_total"}},
+		{expr: `sum without(instance) (node_load1)`, want: []string{"node_load1"}},
+		{expr: `sum(node_load1) without (instance)`, want: []string{"node_load1"}},
+		{expr: `errors_total / on (job) group_left requests_total`, want: []string{"errors_total", "requests_total"}},
+		{expr: `errors_total / ignoring(code) group_left(team) requests_total`, want: []string{"errors_total", "requests_total"}},
+		{expr: `a unless on(job) b and c`, want: []string{"a", "b", "c"}},
+		{expr: `{__name__="process_cpu_seconds_total", job="api"}`, want: []string{"process_cpu_seconds_total"}},
+		{expr: `{__name__=~"process_.*"}`, want: nil},
+		{expr: `histogram_quantile(0.9, sum by (le) (rate(latency_bucket{le!="+Inf"}[5m] offset 1h)))`, want: []string{"latency_bucket"}},
+		{expr: `label_replace(up, "dst", "$1", "src", "(.*)") > bool 0`, want: []string{"up"}},
+		{expr: "up # by (job) comment\n+ up", want: []string{"up"}},
+		{expr: `max_over_time(deriv(rate(x[1m])[5m:1m])[1h:])`, want: []string{"x"}},
+		{expr: `vector(1) + time()`, want: nil},
+		{expr: `sort_by_label(up, "job")`, want: []string{"up"}},
+		{expr: `sum(rate(x[5m])`, wantErr: true},
+		{expr: `rate(x[$__rate_interval])`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := promqlMetricNames(tt.expr)
+			if parser.EnableExperimentalFunctions {
+				t.Fatal("promqlMetricNames() changes the global parser settings")
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("promqlMetricNames() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("promqlMetricNames() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	d := dashboardCheck{
+		values:   map[string]string{"job": "api", "env": "prod", "pod": ".*"},
+		patterns: map[string]bool{"pod": true},
+	}
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{expr: `up{job="$job"}`, want: `up{job="api"}`},
+		{expr: `up{job="${job}", env=~"[[env]]"}`, want: `up{job="api", env=~"prod"}`},
+		{expr: `up{job="${job:regex}"}`, want: `up{job="api"}`},
+		{expr: `rate(x{job="$job"}[$__rate_interval])`, want: `rate(x{job="api"}[1m])`},
+		{expr: `up{pod="$pod"}`, want: `up{pod=~".*"}`},
+		{expr: `up{pod = "$pod", job="$job"}`, want: `up{pod =~ ".*", job="api"}`},
+		{expr: `up{pod!="$pod"}`, want: `up{pod!~".*"}`},
+		{expr: `up{pod=~"$pod"}`, want: `up{pod=~".*"}`},
+		{expr: `up{pod="fixed", name="$unknown"}`, want: `up{pod="fixed", name="$unknown"}`},
+		{expr: `count_values("pod", up) == 1`, want: `count_values("pod", up) == 1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if got := d.substitute(tt.expr); got != tt.want {
+				t.Errorf("substitute() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryReportsParseErrorWithoutPrometheus(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+	}))
+	t.Cleanup(srv.Close)
+	d := dashboardCheck{prom: checkPrometheus{url: srv.URL, client: srv.Client(), now: time.Now()}}
+	if d.query(context.Background(), "Requests", "A", `sum(rate(x[5m])`) {
+		t.Error("query() of an invalid expression = true, want false")
+	}
+	if called {
+		t.Error("invalid expression is sent to prometheus")
+	}
+	if len(d.issues) != 1 || d.issues[0].Kind != CheckParseError || !strings.Contains(d.issues[0].Message, "unclosed") {
+		t.Errorf("issues = %+v, want one parse error", d.issues)
+	}
+}
+
+func TestCheckExitCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		result string
+		want   int
+	}{
+		{name: "variable without sample is a warning", result: `[{"metric":{},"value":[0,"1"]}]`, want: 0},
+		{name: "empty result is a problem", result: `[]`, want: CheckExitCodeProblems},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/v1/label/__name__/values":
+					w.Write([]byte(`{"status":"success","data":["up"]}`))
+				case "/api/v1/query":
+					w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":` + tt.result + `}}`))
+				default:
+					w.Write([]byte(`{"status":"success","data":[]}`))
+				}
+			}))
+			t.Cleanup(srv.Close)
+			app, err := NewCli("test", DashboardBuilder(func(folderName string, c *cli.Command) ([]dashboard.Dashboard, error) {
+				d, err := dashboard.NewDashboardBuilder("Api").
+					Uid("api").
+					WithVariable(dashboard.NewQueryVariableBuilder("job").Query(dashboard.StringOrMap{String: cog.ToPtr("label_values(up, job)")})).
+					WithPanel(timeseries.NewPanelBuilder().Title("Up").WithTarget(query.PrometheusQuery(`up{job="$job"}`, "up"))).
+					Build()
+				return []dashboard.Dashboard{d}, err
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := exitCode(t, app, "dashboard", "check", "--prometheus-url", srv.URL); got != tt.want {
+				t.Errorf("exit code = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,9 @@
 package grafanasdkclistarter
 
 import (
+	"bytes"
 	"context"
 	_ "embed"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -14,6 +14,7 @@ import (
 	"slices"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
@@ -27,7 +28,6 @@ import (
 	"github.com/go-openapi/runtime"
 	"github.com/go-openapi/strfmt"
 	"github.com/grafana/grafana-foundation-sdk/go/cog/plugins"
-	"github.com/grafana/grafana-foundation-sdk/go/dashboard"
 	goapi "github.com/grafana/grafana-openapi-client-go/client"
 	"github.com/grafana/grafana-openapi-client-go/models"
 
@@ -37,30 +37,94 @@ import (
 type CliValues = string
 
 const (
-	CliServer            CliValues = "server"
-	CliApiKey            CliValues = "apikey"
-	CliApiBasePath       CliValues = "apibasepath"
-	CliFolderName        CliValues = "foldername"
-	CliYamlTargetFile    CliValues = "file"
-	CliDevDatasourceName string    = "datasource_name"
-	CliDevSubnet         string    = "subnet"
-	CliDevGateway                  = "gateway"
+	CliServer                CliValues = "server"
+	CliApiKey                CliValues = "apikey"
+	CliApiBasePath           CliValues = "apibasepath"
+	CliFolderName            CliValues = "foldername"
+	CliYamlTargetFile        CliValues = "file"
+	CliDetailedExitCode      CliValues = "detailed-exitcode"
+	CliPrune                 CliValues = "prune"
+	CliPruneTag              CliValues = "prune-tag"
+	CliDryRun                CliValues = "dry-run"
+	CliCommit                CliValues = "commit"
+	CliAdopt                 CliValues = "adopt"
+	CliDisableProvenance     CliValues = "disable-provenance"
+	CliExportUid             CliValues = "uid"
+	CliExportFolder          CliValues = "folder-uid"
+	CliExportTag             CliValues = "tag"
+	CliExportOutput          CliValues = "output"
+	CliExportPackage         CliValues = "package"
+	CliDevDatasourceName     string    = "datasource_name"
+	CliDevSubnet             string    = "subnet"
+	CliDevGateway                      = "gateway"
+	CliDevLoki               CliValues = "loki"
+	CliDevTracing            CliValues = "tracing"
+	CliDevAlertmanager       CliValues = "alertmanager"
+	CliDevWatch              CliValues = "watch"
+	CliDevNoApply            CliValues = "no-apply"
+	CliDevJob                CliValues = "job"
+	CliDevScrapePort         CliValues = "scrape-port"
+	CliDevMetricsPath        CliValues = "metrics-path"
+	CliDevScheme             CliValues = "scheme"
+	CliDevForce              CliValues = "force"
+	CliDevHost               CliValues = "host"
+	CliDevDetach             CliValues = "detach"
+	CliDevPersist            CliValues = "persist"
+	CliDevGrafanaImage       CliValues = "grafana-image"
+	CliDevPrometheusImage    CliValues = "prometheus-image"
+	CliDevLokiImage          CliValues = "loki-image"
+	CliDevTempoImage         CliValues = "tempo-image"
+	CliDevOtelCollectorImage CliValues = "otel-collector-image"
+	CliDevAlertmanagerImage  CliValues = "alertmanager-image"
+	CliDevGrafanaEnv         CliValues = "grafana-env"
+	CliDevFeatureToggles     CliValues = "feature-toggles"
+	CliDevPlugins            CliValues = "plugins"
+	CliDevAnonymous          CliValues = "anonymous"
+	CliDevAdminUser          CliValues = "admin-user"
+	CliDevAdminPassword      CliValues = "admin-password"
+	CliDevBackfillFile       CliValues = "backfill-file"
+	CliDevBackfillRange      CliValues = "backfill-range"
+	CliDevBackfillStep       CliValues = "backfill-step"
+	CliDevSyntheticFile      CliValues = "synthetic-file"
+	CliDevSyntheticPort      CliValues = "synthetic-port"
+	CliDevTestData           CliValues = "testdata"
+	CliDevTestDataScenario   CliValues = "testdata-scenario"
+	CliCheckPrometheusUrl    CliValues = "prometheus-url"
+	CliCheckVar              CliValues = "var"
 )
 
 //go:embed prometheus.yml.tmpl
-var prometheusTmpl []byte
+var prometheusTmpl string
+
+//go:embed record_rules.yaml
+var recordRulesTmpl []byte
 
 type Option func(runner *Runner, app *cli.Command) error
 
 type Runner struct {
-	cfg       *goapi.TransportConfig
-	client    *goapi.GrafanaHTTPAPI
-	Dashboard DashboardCreator
+	appName               string
+	cfg                   *goapi.TransportConfig
+	client                *goapi.GrafanaHTTPAPI
+	knownFolders          map[string]bool
+	knownUsers            map[string]*models.UserProfileDTO
+	Dashboard             DashboardCreator
+	FolderDashboards      FolderDashboardCreator
+	Permissions           PermissionCreator
+	Datasources           DatasourceCreator
+	LibraryPanels         LibraryPanelCreator
+	AlertRules            AlertRuleCreator
+	ContactPoints         ContactPointCreator
+	NotificationPolicy    NotificationPolicyCreator
+	MuteTimings           MuteTimingCreator
+	NotificationTemplates NotificationTemplateCreator
+	devScrapeTargets      []ScrapeTarget
+	devBackfill           []BackfillSeries
+	devSynthetic          []SyntheticMetric
 }
 
 func NewCli(appName string, options ...Option) (*cli.Command, error) {
 	plugins.RegisterDefaultPlugins()
-	runner := Runner{}
+	runner := Runner{appName: appName}
 
 	applyDestroyFlags := []cli.Flag{
 
@@ -83,6 +147,24 @@ func NewCli(appName string, options ...Option) (*cli.Command, error) {
 		},
 	}
 
+	disableProvenanceFlag := &cli.BoolFlag{
+		Name:    CliDisableProvenance,
+		Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDisableProvenance, appName)),
+		Usage:   "keep provisioned objects editable at the grafana ui (sends X-Disable-Provenance)",
+	}
+	adoptFlag := &cli.BoolFlag{
+		Name:    CliAdopt,
+		Sources: cli.EnvVars(GetFlagEnvByFlagName(CliAdopt, appName)),
+		Usage:   "take over existing dashboards with the same uid which are not managed by any app",
+	}
+	planFlags := append([]cli.Flag{
+		&cli.BoolFlag{
+			Name:    CliDetailedExitCode,
+			Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDetailedExitCode, appName)),
+			Usage:   "exit with 0 if nothing changes, 2 if changes are pending and 1 on errors",
+		},
+	}, applyDestroyFlags...)
+
 	app := &cli.Command{
 		Usage: fmt.Sprintf("%s-grafana sdk cli", appName),
 		Commands: []*cli.Command{
@@ -102,7 +184,29 @@ func NewCli(appName string, options ...Option) (*cli.Command, error) {
 						Before: runner.Before,
 						Action: runner.Apply,
 						Usage:  "Upload Dashboard to target configuration",
-						Flags:  applyDestroyFlags,
+						Flags: append([]cli.Flag{
+							&cli.BoolFlag{
+								Name:    CliPrune,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliPrune, appName)),
+								Usage:   "delete dashboards inside the folder which are not part of the code anymore",
+							},
+							adoptFlag,
+							&cli.StringFlag{
+								Name:    CliPruneTag,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliPruneTag, appName)),
+								Usage:   "prune all dashboards with this tag instead of all dashboards inside the folder",
+							},
+							&cli.BoolFlag{
+								Name:    CliDryRun,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDryRun, appName)),
+								Usage:   "only print what would be applied and deleted",
+							},
+							&cli.StringFlag{
+								Name:    CliCommit,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliCommit, appName), "GITHUB_SHA", "CI_COMMIT_SHA"),
+								Usage:   "git commit stored at the dashboards (default: detected from build info or git)",
+							},
+						}, applyDestroyFlags...),
 					},
 					{
 						Name:   "destroy",
@@ -112,44 +216,387 @@ func NewCli(appName string, options ...Option) (*cli.Command, error) {
 						Flags:  applyDestroyFlags,
 					},
 					{
-						Name:   "plan",
-						Action: runner.Plan,
-						Usage:  "Upload Dashboard to target configuration",
+						Name:         "plan",
+						Action:       runner.Plan,
+						Before:       runner.PlanBefore,
+						OnUsageError: planUsageError,
+						Usage:        "Show what apply would change at target configuration",
+						Flags:        append([]cli.Flag{adoptFlag}, planFlags...),
+					},
+					{
+						Name:   "check",
+						Action: runner.Check,
+						Usage:  "Run every prometheus query of the dashboards and report parse errors, empty results and unknown metrics",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    CliCheckPrometheusUrl,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliCheckPrometheusUrl, appName)),
+								Usage:   "prometheus to run the queries at, the one of the detached dev stack if empty",
+							},
+							&cli.StringSliceFlag{
+								Name:    CliCheckVar,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliCheckVar, appName)),
+								Usage:   "sample value of a dashboard variable as name=value, default is its current value or the first value of its query",
+							},
+						},
+					},
+					{
+						Name:   "export",
+						Action: runner.Export,
+						Before: runner.Before,
+						Usage:  "Generate go builder code of existing dashboards",
+						Flags: append([]cli.Flag{
+							&cli.StringSliceFlag{
+								Name:  CliExportUid,
+								Usage: "uid of a dashboard to export",
+							},
+							&cli.StringFlag{
+								Name:  CliExportFolder,
+								Usage: "export all dashboards of the folder with this uid",
+							},
+							&cli.StringSliceFlag{
+								Name:  CliExportTag,
+								Usage: "export all dashboards with this tag",
+							},
+							&cli.StringFlag{
+								Name:  CliExportOutput,
+								Value: "./dashboards",
+								Usage: "directory of the generated go files",
+							},
+							&cli.StringFlag{
+								Name:  CliExportPackage,
+								Value: "dashboards",
+								Usage: "package name of the generated go files",
+							},
+						}, applyDestroyFlags...),
+					},
+				},
+			},
+			{
+				Name:  "datasource",
+				Usage: "To apply destroy and plan datasources",
+				Commands: []*cli.Command{
+					{
+						Name:   "apply",
+						Before: runner.Before,
+						Action: runner.DatasourceApply,
+						Usage:  "Upsert datasources at target configuration",
+						Flags:  applyDestroyFlags,
+					},
+					{
+						Name:   "destroy",
+						Before: runner.Before,
+						Action: runner.DatasourceDestroy,
+						Usage:  "Remove datasources from target configuration",
+						Flags:  applyDestroyFlags,
+					},
+					{
+						Name:         "plan",
+						Before:       runner.PlanBefore,
+						Action:       runner.DatasourcePlan,
+						OnUsageError: planUsageError,
+						Usage:        "Show what apply would change at target configuration",
+						Flags:        planFlags,
+					},
+				},
+			},
+			{
+				Name:  "alert",
+				Usage: "To apply destroy and plan current alert rules",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    CliFolderName,
+						Sources: cli.EnvVars(GetFlagEnvByFlagName(CliFolderName, appName)),
+						Usage:   "GrafanaFolder to create alert rules",
+					},
+				},
+				Commands: []*cli.Command{
+					{
+						Name:   "apply",
+						Before: runner.Before,
+						Action: runner.AlertApply,
+						Usage:  "Upload alert rule groups to target configuration",
+						Flags:  append([]cli.Flag{disableProvenanceFlag}, applyDestroyFlags...),
+					},
+					{
+						Name:   "destroy",
+						Before: runner.Before,
+						Action: runner.AlertDestroy,
+						Usage:  "Remove alert rule groups from target configuration",
+						Flags:  applyDestroyFlags,
+					},
+					{
+						Name:         "plan",
+						Before:       runner.PlanBefore,
+						Action:       runner.AlertPlan,
+						OnUsageError: planUsageError,
+						Usage:        "Show what apply would change at target configuration",
+						Flags:        planFlags,
+					},
+				},
+			},
+			{
+				Name:  "notification",
+				Usage: "To apply destroy and plan contact points, notification policies, mute timings and templates",
+				Commands: []*cli.Command{
+					{
+						Name:   "apply",
+						Before: runner.Before,
+						Action: runner.NotificationApply,
+						Usage:  "Upload notification configuration to target configuration",
+						Flags:  append([]cli.Flag{disableProvenanceFlag}, applyDestroyFlags...),
+					},
+					{
+						Name:   "destroy",
+						Before: runner.Before,
+						Action: runner.NotificationDestroy,
+						Usage:  "Remove notification configuration from target configuration",
+						Flags:  append([]cli.Flag{disableProvenanceFlag}, applyDestroyFlags...),
+					},
+					{
+						Name:         "plan",
+						Before:       runner.PlanBefore,
+						Action:       runner.NotificationPlan,
+						OnUsageError: planUsageError,
+						Usage:        "Show what apply would change at target configuration",
+						Flags:        planFlags,
 					},
 				},
 			},
 			{
 				Name:   "dev",
 				Before: runner.BeforeDev,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    CliDevSubnet,
+						Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevSubnet, appName)),
+						Usage:   "subnet of the dev network, a free one is detected if empty",
+					},
+					&cli.StringFlag{
+						Name:    CliDevGateway,
+						Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevGateway, appName)),
+						Usage:   "gateway of the dev network, the first address of the subnet if empty",
+					},
+					&cli.StringFlag{
+						Name:    CliDevHost,
+						Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevHost, appName)),
+						Usage:   "address the containers reach your app with, " + DevHostAlias + " if empty",
+					},
+					&cli.StringFlag{
+						Name:    CliDevJob,
+						Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevJob, appName)),
+						Value:   appName,
+						Usage:   "prometheus job name of your app",
+					},
+					&cli.IntSliceFlag{
+						Name:    CliDevScrapePort,
+						Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevScrapePort, appName)),
+						Usage:   "port of your app prometheus scrapes, every port is a target of the job",
+					},
+					&cli.StringFlag{
+						Name:    CliDevMetricsPath,
+						Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevMetricsPath, appName)),
+						Value:   "/metrics",
+					},
+					&cli.StringFlag{
+						Name:    CliDevScheme,
+						Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevScheme, appName)),
+						Value:   "http",
+					},
+					&cli.BoolFlag{
+						Name:    CliDevForce,
+						Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevForce, appName)),
+						Usage:   "regenerate the prometheus files even if they exist",
+					},
+					&cli.StringFlag{
+						Name:    CliDevSyntheticFile,
+						Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevSyntheticFile, appName)),
+						Usage:   "yaml file of synthetic metrics served to prometheus without a running app",
+					},
+					&cli.IntFlag{
+						Name:    CliDevSyntheticPort,
+						Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevSyntheticPort, appName)),
+						Value:   9101,
+						Usage:   "port of the synthetic metrics exporter",
+					},
+				},
 				Commands: []*cli.Command{
 					{
 						Name:   "init",
 						Usage:  "Generate template prometheus folder/file to configure scrape stuff for local dev server (DO NOT move this files and start dev server from same path)",
 						Action: runner.InitDev,
 					},
+					{
+						Name:   "targets",
+						Usage:  "Print the dev scrape targets of the DevScrapeTarget options as json",
+						Hidden: true,
+						Action: runner.PrintDevScrapeTargets,
+					},
+					{
+						Name:   "apply",
+						Usage:  "Apply the dashboards to the dev grafana like dev run does, used by dev run --watch",
+						Hidden: true,
+						Before: runner.Before,
+						Action: runner.Apply,
+						Flags: append([]cli.Flag{
+							&cli.StringFlag{
+								Name:  CliFolderName,
+								Usage: "GrafanaFolder to create dashboards",
+							},
+							&cli.BoolFlag{
+								Name:  CliPrune,
+								Usage: "delete dashboards inside the folder which are not part of the code anymore",
+							},
+							&cli.StringFlag{
+								Name:  CliPruneTag,
+								Usage: "prune all dashboards with this tag instead of all dashboards inside the folder",
+							},
+							&cli.StringFlag{
+								Name:  CliDevTestDataScenario,
+								Usage: "swap every prometheus query for this testdata scenario",
+							},
+						}, applyDestroyFlags...),
+					},
 					{
 						Name:  "run",
 						Usage: "Start DEV prometheus and grafana",
-						Flags: []cli.Flag{
+						Flags: append([]cli.Flag{
 							&cli.StringFlag{
 								Name:     CliDevDatasourceName,
 								Sources:  cli.EnvVars(GetFlagEnvByFlagName(CliDevDatasourceName, appName)),
 								Aliases:  []string{"datasource"},
 								Required: true,
 							},
+							&cli.BoolFlag{
+								Name:    CliDevLoki,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevLoki, appName)),
+								Usage:   "start loki and provision it as datasource",
+							},
+							&cli.BoolFlag{
+								Name:    CliDevTracing,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevTracing, appName)),
+								Usage:   "start tempo and an otel collector, enable prometheus exemplars and provision tempo as datasource",
+							},
+							&cli.BoolFlag{
+								Name:    CliDevAlertmanager,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevAlertmanager, appName)),
+								Usage:   "start alertmanager and print its notifications",
+							},
+							&cli.BoolFlag{
+								Name:    CliDevWatch,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevWatch, appName)),
+								Usage:   "rebuild and apply the dashboards to the dev grafana on every change of a go file",
+							},
+							&cli.BoolFlag{
+								Name:    CliDevNoApply,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevNoApply, appName)),
+								Usage:   "do not apply the dashboards to the dev grafana on startup",
+							},
+							&cli.BoolFlag{
+								Name:    CliDevTestData,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevTestData, appName)),
+								Usage:   fmt.Sprintf("provision the testdata datasource (uid %s)", DevTestDataDatasourceUID),
+							},
+							&cli.StringFlag{
+								Name:    CliDevTestDataScenario,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevTestDataScenario, appName)),
+								Usage:   fmt.Sprintf("swap every prometheus query for a testdata scenario while applying (%s)", strings.Join(testDataScenarios, ", ")),
+							},
+							&cli.BoolFlag{
+								Name:    CliDevDetach,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevDetach, appName)),
+								Usage:   "keep the dev stack running in background, see dev status, dev env and dev stop",
+							},
+							&cli.BoolFlag{
+								Name:    CliDevPersist,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevPersist, appName)),
+								Usage:   "keep the prometheus and grafana data in named volumes across dev runs, see dev reset",
+							},
+							&cli.StringSliceFlag{
+								Name:    CliDevGrafanaEnv,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevGrafanaEnv, appName)),
+								Usage:   "extra env of the dev grafana as KEY=VALUE",
+							},
+							&cli.StringSliceFlag{
+								Name:    CliDevFeatureToggles,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevFeatureToggles, appName)),
+								Usage:   "feature toggles to enable at the dev grafana",
+							},
+							&cli.StringSliceFlag{
+								Name:    CliDevPlugins,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevPlugins, appName)),
+								Usage:   "plugins to install at the dev grafana (GF_INSTALL_PLUGINS syntax)",
+							},
+							&cli.BoolFlag{
+								Name:    CliDevAnonymous,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevAnonymous, appName)),
+								Usage:   "allow anonymous access with admin role to the dev grafana",
+							},
 							&cli.StringFlag{
-								Name:    CliDevSubnet,
-								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevSubnet, appName)),
-								Value:   "192.168.192.0/20",
+								Name:    CliDevAdminUser,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevAdminUser, appName)),
+								Value:   "admin",
 							},
 							&cli.StringFlag{
-								Name:    CliDevGateway,
-								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevGateway, appName)),
-								Value:   "192.168.192.1",
+								Name:    CliDevAdminPassword,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevAdminPassword, appName)),
+								Value:   "admin",
 							},
-						},
+							&cli.StringFlag{
+								Name:    CliFolderName,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliFolderName, appName)),
+								Usage:   "GrafanaFolder to create dashboards",
+							},
+							&cli.BoolFlag{
+								Name:    CliPrune,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliPrune, appName)),
+								Usage:   "delete dashboards inside the folder which are not part of the code anymore, on startup and on every watch apply",
+							},
+							&cli.StringFlag{
+								Name:    CliPruneTag,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliPruneTag, appName)),
+								Usage:   "prune all dashboards with this tag instead of all dashboards inside the folder",
+							},
+							&cli.StringFlag{
+								Name:    CliDevBackfillFile,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevBackfillFile, appName)),
+								Usage:   "openmetrics file backfilled into prometheus before it starts",
+							},
+							&cli.DurationFlag{
+								Name:    CliDevBackfillRange,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevBackfillRange, appName)),
+								Value:   7 * 24 * time.Hour,
+								Usage:   "history generated for the DevBackfill series",
+							},
+							&cli.DurationFlag{
+								Name:    CliDevBackfillStep,
+								Sources: cli.EnvVars(GetFlagEnvByFlagName(CliDevBackfillStep, appName)),
+								Value:   time.Minute,
+								Usage:   "interval of the samples generated for the DevBackfill series",
+							},
+						}, devImageFlagList(appName)...),
 						Action: runner.startDev,
 					},
+					{
+						Name:   "status",
+						Usage:  "Show the containers of the dev stack",
+						Action: runner.DevStatus,
+					},
+					{
+						Name:   "env",
+						Usage:  "Print the exports to use the detached dev stack",
+						Action: runner.DevEnv,
+					},
+					{
+						Name:   "stop",
+						Usage:  "Stop and remove the dev stack",
+						Action: runner.DevStop,
+					},
+					{
+						Name:   "reset",
+						Usage:  "Delete the persisted prometheus and grafana data of the dev stack",
+						Action: runner.DevReset,
+					},
 				},
 			},
 		},
@@ -190,25 +637,76 @@ func (r *Runner) Before(ctx context.Context, c *cli.Command) (context.Context, e
 
 func (r *Runner) Apply(ctx context.Context, c *cli.Command) error {
 	foldername := c.String(CliFolderName)
-	_, err := r.client.Folders.GetFolderByUID(foldername)
+	dryRun := c.Bool(CliDryRun)
+	dashboards, err := r.getDashboards(ctx, c)
 	if err != nil {
-		_, err := r.client.Folders.CreateFolder(&models.CreateFolderCommand{
-			UID:   foldername,
-			Title: foldername,
-		})
+		return fmt.Errorf("failed apply Dashboard %w", err)
+	}
+	// nothing is written if one dashboard must not be overwritten
+	err = r.checkDashboardOwners(dashboards, c.Bool(CliAdopt))
+	if err != nil {
+		return fmt.Errorf("apply: refuse to overwrite: %w", err)
+	}
+	if dryRun {
+		for _, d := range dashboards {
+			fmt.Printf("%s: would be applied to %s\n", dashboardTitle(d.Dashboard), d.Folder)
+		}
+	} else {
+		panels, err := r.getLibraryPanels(c)
 		if err != nil {
-			return fmt.Errorf("apply: can not create folder %s: %w", foldername, err)
+			return fmt.Errorf("failed apply Dashboard %w", err)
+		}
+		err = r.applyLibraryPanels(panels)
+		if err != nil {
+			return err
+		}
+		owner := OwnerMetadata{
+			App:       r.appName,
+			Commit:    currentCommit(c.String(CliCommit)),
+			AppliedAt: time.Now().UTC(),
+		}
+		scenario, err := testDataScenarioOf(c)
+		if err != nil {
+			return err
+		}
+		err = r.applyDashboards(dashboards, owner, scenario)
+		if err != nil {
+			return err
+		}
+		err = r.applyPermissions(ctx, c)
+		if err != nil {
+			return fmt.Errorf("failed apply permissions: %w", err)
 		}
 	}
-
-	dashboards, err := r.getDashboards(ctx, c)
-	if err != nil {
-		return fmt.Errorf("failed apply Dashboard %w", err)
+	if c.Bool(CliPrune) {
+		err = r.prune(ctx, foldername, c.String(CliPruneTag), dashboards, dryRun)
+		if err != nil {
+			return fmt.Errorf("failed prune: %w", err)
+		}
 	}
-	for _, d := range dashboards {
+	return nil
+}
+
+// applyDashboards posts the dashboards, with a testdata scenario their prometheus queries are swapped for it
+func (r *Runner) applyDashboards(dashboards []FolderDashboard, owner OwnerMetadata, scenario string) error {
+	for _, fd := range dashboards {
+		d := fd.Dashboard
+		folderUID, err := r.ensureFolderPath(fd.Folder)
+		if err != nil {
+			return err
+		}
+		body, err := normalizeDashboard(d)
+		if err != nil {
+			return err
+		}
+		if scenario != "" {
+			swapTestDataQueries(body, scenario)
+		}
+		stampOwner(body, owner)
 		p, err := r.client.Dashboards.PostDashboard(&models.SaveDashboardCommand{
-			Dashboard: d,
-			FolderUID: foldername,
+			Dashboard: body,
+			FolderUID: folderUID,
+			Message:   fmt.Sprintf("applied by %s (commit %s)", owner.App, owner.Commit),
 			Overwrite: true,
 		})
 		if err != nil {
@@ -227,20 +725,6 @@ func (r *Runner) Apply(ctx context.Context, c *cli.Command) error {
 	return nil
 }
 
-func (r *Runner) Plan(ctx context.Context, c *cli.Command) error {
-	dashboards, err := r.getDashboards(ctx, c)
-	if err != nil {
-		return fmt.Errorf("failed plan %w ", err)
-	}
-	for _, d := range dashboards {
-		b, err := json.MarshalIndent(d, " ", "    ")
-		if err != nil {
-			return fmt.Errorf("unable to marshal: %w´", err)
-		}
-		fmt.Println(string(b))
-	}
-	return nil
-}
 func (r *Runner) Destroy(ctx context.Context, c *cli.Command) error {
 	dashboards, err := r.getDashboards(ctx, c)
 	if err != nil {
@@ -248,13 +732,33 @@ func (r *Runner) Destroy(ctx context.Context, c *cli.Command) error {
 	}
 
 	errList := errors.Join(nil)
-	for _, d := range dashboards {
-
-		_, err := r.client.Dashboards.DeleteDashboardByUID(*d.Uid)
+	for _, d := range folderDashboards(dashboards) {
+		if d.Uid == nil {
+			continue
+		}
+		remote, _, found, err := r.getRemoteDashboard(*d.Uid)
+		if err != nil {
+			errList = errors.Join(errList, err)
+			continue
+		}
+		if !found {
+			continue
+		}
+		if owner := dashboardOwner(remote); owner != r.appName {
+			errList = errors.Join(errList, fmt.Errorf("refuse to delete dashboard %s: not managed by %s", *d.Uid, r.appName))
+			continue
+		}
+		_, err = r.client.Dashboards.DeleteDashboardByUID(*d.Uid)
 		if err != nil {
 			errList = errors.Join(errList, err)
 		}
+	}
 
+	panels, err := r.getLibraryPanels(c)
+	if err != nil {
+		errList = errors.Join(errList, err)
+	} else {
+		errList = errors.Join(errList, r.destroyLibraryPanels(panels))
 	}
 
 	if errList != nil {
@@ -265,13 +769,32 @@ func (r *Runner) Destroy(ctx context.Context, c *cli.Command) error {
 	return nil
 }
 
-func (r *Runner) getDashboards(ctx context.Context, c *cli.Command) ([]dashboard.Dashboard, error) {
+// getDashboards returns the dashboards of all creators with the full folder path
+func (r *Runner) getDashboards(ctx context.Context, c *cli.Command) ([]FolderDashboard, error) {
 	foldername := c.String(CliFolderName)
-	dashboards, err := r.Dashboard(foldername, c)
-	if err != nil {
-		return nil, fmt.Errorf("failed get Dashboard %w", err)
+	if r.Dashboard == nil && r.FolderDashboards == nil {
+		return nil, fmt.Errorf("no DashboardCreator set, use DashboardBuilder or FolderDashboardBuilder option")
+	}
+	var res []FolderDashboard
+	if r.Dashboard != nil {
+		dashboards, err := r.Dashboard(foldername, c)
+		if err != nil {
+			return nil, fmt.Errorf("failed get Dashboard %w", err)
+		}
+		for _, d := range dashboards {
+			res = append(res, FolderDashboard{Folder: JoinFolderPath(foldername), Dashboard: d})
+		}
+	}
+	if r.FolderDashboards != nil {
+		dashboards, err := r.FolderDashboards(foldername, c)
+		if err != nil {
+			return nil, fmt.Errorf("failed get Dashboard %w", err)
+		}
+		for _, d := range dashboards {
+			res = append(res, FolderDashboard{Folder: JoinFolderPath(foldername, d.Folder), Dashboard: d.Dashboard})
+		}
 	}
-	return dashboards, nil
+	return res, nil
 }
 
 func (r *Runner) InitDev(ctx context.Context, c *cli.Command) error {
@@ -279,8 +802,26 @@ func (r *Runner) InitDev(ctx context.Context, c *cli.Command) error {
 	if err != nil {
 		return err
 	}
-	if !DirExist("./prometheus/prometheus.yml") {
-		err = os.WriteFile("./prometheus/prometheus.yml", prometheusTmpl, os.ModePerm)
+	force := c.Bool(CliDevForce)
+	data, err := r.devPrometheusConfig(c)
+	if err != nil {
+		return err
+	}
+	config, err := renderPrometheusConfig(data)
+	if err != nil {
+		return err
+	}
+	// an existing config may be edited by hand
+	if force || !DirExist(devPrometheusConfigFile) {
+		err = os.WriteFile(devPrometheusConfigFile, config, os.ModePerm)
+		if err != nil {
+			return err
+		}
+	} else if current, err := os.ReadFile(devPrometheusConfigFile); err == nil && len(data.Targets) > 0 && !bytes.Equal(current, config) {
+		fmt.Printf("%s is kept, use --%s to regenerate it from the scrape targets\n", devPrometheusConfigFile, CliDevForce)
+	}
+	if force || !DirExist(devRecordRulesFile) {
+		err = os.WriteFile(devRecordRulesFile, recordRulesTmpl, os.ModePerm)
 		if err != nil {
 			return err
 		}
@@ -315,22 +856,59 @@ func DefaultDevRunDataSource(value string) Option {
 }
 
 func (r *Runner) startDev(ctx context.Context, c *cli.Command) error {
-	err := r.InitDev(ctx, c)
+	settings, err := devRunSettingsOf(c)
+	if err != nil {
+		return err
+	}
+	scenario, err := testDataScenarioOf(c)
 	if err != nil {
 		return err
 	}
+	err = r.InitDev(ctx, c)
+	if err != nil {
+		return err
+	}
+	backfill, err := r.backfillFiles(c, time.Now())
+	if err != nil {
+		return err
+	}
+	synthetic, err := r.syntheticMetrics(c)
+	if err != nil {
+		return err
+	}
+	detach, persist := c.Bool(CliDevDetach), c.Bool(CliDevPersist)
+	// detached is set once the stack is up, all deferred teardowns keep the containers then
+	detached := false
+	if detach {
+		if err := r.prepareDetach(ctx, c); err != nil {
+			return err
+		}
+	}
+	if len(synthetic) > 0 {
+		exporter, err := startSyntheticExporter(synthetic, int(c.Int(CliDevSyntheticPort)))
+		if err != nil {
+			return err
+		}
+		defer exporter.Close()
+	}
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, syscall.SIGINT, syscall.SIGTERM)
 	pwd, err := os.Getwd()
 	if err != nil {
 		return err
 	}
+	subnet, gateway, err := devSubnet(ctx, c)
+	if err != nil {
+		return err
+	}
+	stack := devStack{app: r.appName}
 	newNetwork, err := testContainerNetwork.New(ctx,
+		testContainerNetwork.WithLabels(stack.labels("network")),
 		testContainerNetwork.WithIPAM(&network.IPAM{
 			Config: []network.IPAMConfig{
 				{
-					Subnet:  c.String(CliDevSubnet),
-					Gateway: c.String(CliDevGateway),
+					Subnet:  subnet,
+					Gateway: gateway,
 				},
 			},
 		}),
@@ -339,29 +917,50 @@ func (r *Runner) startDev(ctx context.Context, c *cli.Command) error {
 		return err
 	}
 	defer func() {
+		if detached {
+			return
+		}
 		if err := newNetwork.Remove(ctx); err != nil {
 			panic(err)
 		}
 	}()
+	stack.network = newNetwork.Name
 	prometheusContainerName := "prometheus_" + uuid.New().String()
 	prometheusPort := "9090/tcp"
+	tracing := c.Bool(CliDevTracing)
+	prometheusCmd := []string{
+		"--config.file=/etc/prometheus/prometheus.yml",
+		"--storage.tsdb.path=/prometheus",
+		"--web.console.libraries=/usr/share/prometheus/console_libraries",
+		"--web.console.templates=/usr/share/prometheus/consoles",
+		"--web.enable-lifecycle",
+	}
+	if tracing {
+		// span metrics and otlp metrics are written by remote write, exemplars link them to the traces
+		prometheusCmd = append(prometheusCmd, "--enable-feature=exemplar-storage", "--web.enable-remote-write-receiver")
+	}
 	req := testcontainers.ContainerRequest{
 		Name:         prometheusContainerName,
-		Image:        "prom/prometheus:latest",
+		Image:        settings.images["prometheus"],
 		ExposedPorts: []string{prometheusPort},
-		Cmd: []string{
-			"--config.file=/etc/prometheus/prometheus.yml",
-			"--storage.tsdb.path=/prometheus",
-			"--web.console.libraries=/usr/share/prometheus/console_libraries",
-			"--web.console.templates=/usr/share/prometheus/consoles",
-			"--web.enable-lifecycle",
-		},
+		Cmd:          prometheusCmd,
 		HostConfigModifier: func(hc *container.HostConfig) {
 			hc.Mounts = append(hc.Mounts, mount.Mount{Source: path.Join(pwd, "prometheus"), Target: "/etc/prometheus", Type: mount.TypeBind})
+			if persist {
+				hc.Mounts = append(hc.Mounts, stack.volume("prometheus", "/prometheus"))
+			}
+			withHostGateway(hc)
 		},
-		Privileged: true,
-		Networks:   []string{newNetwork.Name},
-		WaitingFor: wait.ForListeningPort(nat.Port(prometheusPort)),
+		Labels:         stack.labels("prometheus"),
+		Privileged:     true,
+		Networks:       []string{newNetwork.Name},
+		NetworkAliases: map[string][]string{newNetwork.Name: {prometheusAlias}},
+		WaitingFor:     wait.ForListeningPort(nat.Port(prometheusPort)),
+	}
+	if len(backfill) > 0 {
+		withBackfill(&req, backfill)
+		// prometheus listens once all blocks are created
+		req.WaitingFor = wait.ForListeningPort(nat.Port(prometheusPort)).WithStartupTimeout(10 * time.Minute)
 	}
 	prometheusC, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
 		ContainerRequest: req,
@@ -371,6 +970,9 @@ func (r *Runner) startDev(ctx context.Context, c *cli.Command) error {
 		return fmt.Errorf("error start prometheus: %w", err)
 	}
 	defer func() {
+		if detached {
+			return
+		}
 		if err := prometheusC.Terminate(ctx); err != nil {
 			panic(err)
 		}
@@ -378,11 +980,18 @@ func (r *Runner) startDev(ctx context.Context, c *cli.Command) error {
 	grafanaPort := "3000/tcp"
 
 	req2 := testcontainers.ContainerRequest{
-		Image:        "grafana/grafana:latest",
+		Image:        settings.images["grafana"],
 		ExposedPorts: []string{grafanaPort},
+		Env:          settings.grafanaEnv,
 		Networks:     []string{newNetwork.Name},
+		Labels:       stack.labels("grafana"),
 		WaitingFor:   wait.ForListeningPort(nat.Port(grafanaPort)),
 	}
+	if persist {
+		req2.HostConfigModifier = func(hc *container.HostConfig) {
+			hc.Mounts = append(hc.Mounts, stack.volume("grafana", "/var/lib/grafana"))
+		}
+	}
 	grafanaC, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
 		ContainerRequest: req2,
 		Started:          true,
@@ -391,6 +1000,9 @@ func (r *Runner) startDev(ctx context.Context, c *cli.Command) error {
 		return fmt.Errorf("error start Grafana %w", err)
 	}
 	defer func() {
+		if detached {
+			return
+		}
 		if err := grafanaC.Terminate(ctx); err != nil {
 			panic(err)
 		}
@@ -416,40 +1028,193 @@ func (r *Runner) startDev(ctx context.Context, c *cli.Command) error {
 		BasePath: "/api",
 		// Schemes are the transfer protocols used by the API (http or https).
 		Schemes:   []string{"http"},
-		BasicAuth: url.UserPassword("admin", "admin"),
+		BasicAuth: url.UserPassword(settings.adminUser, settings.adminPassword),
 	}
 	client := goapi.NewHTTPClientWithConfig(strfmt.Default, cfg)
 	// prometheusDatasource, err := prometheus.New(c.String(CliDevDatasourceName), fmt.Sprintf("http://%s:9090", prometheusContainerName))
 	// if err != nil {
 	// 	return err
 	// }
-	_, err = client.Datasources.AddDataSource(&models.AddDataSourceCommand{
+	prometheusDatasource := &models.AddDataSourceCommand{
 		Name:   c.String(CliDevDatasourceName),
 		URL:    fmt.Sprintf("http://%s:9090", prometheusContainerName),
 		UID:    c.String(CliDevDatasourceName),
 		Type:   "prometheus",
 		Access: "proxy",
-	})
+	}
+	if tracing {
+		prometheusDatasource.JSONData = prometheusExemplarJSONData()
+	}
+	err = upsertDevDatasource(client, prometheusDatasource)
 	if err != nil {
 		return fmt.Errorf("error create prometheus datasource at grafana: %w", err)
 	}
+	testData := c.Bool(CliDevTestData) || scenario != ""
+	if testData {
+		if err := addTestDataDatasource(client); err != nil {
+			return err
+		}
+	}
+
+	lokiPushUrl := ""
+	if c.Bool(CliDevLoki) {
+		lokiC, err := startLoki(ctx, stack, settings.images["loki"])
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if detached {
+				return
+			}
+			if err := lokiC.Terminate(ctx); err != nil {
+				panic(err)
+			}
+		}()
+		if err := addLokiDatasource(client, lokiC); err != nil {
+			return err
+		}
+		lokiUrl, err := lokiC.endpoint(ctx, lokiPort)
+		if err != nil {
+			return fmt.Errorf("error get loki endpoint: %w", err)
+		}
+		lokiPushUrl = lokiUrl + "/loki/api/v1/push"
+	}
+
+	otlpGrpcEndpoint, otlpHttpEndpoint := "", ""
+	if tracing {
+		tempoC, collectorC, err := startTracing(ctx, stack, settings.images["tempo"], settings.images["otel-collector"])
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if detached {
+				return
+			}
+			for _, dc := range []devContainer{collectorC, tempoC} {
+				if err := dc.Terminate(ctx); err != nil {
+					panic(err)
+				}
+			}
+		}()
+		if err := addTempoDatasource(client, tempoC, c.String(CliDevDatasourceName), c.Bool(CliDevLoki)); err != nil {
+			return err
+		}
+		grpcPort, err := collectorC.container.MappedPort(ctx, nat.Port(otlpGrpcPort))
+		if err != nil {
+			return fmt.Errorf("unable to get mapped otlp grpc port: %w", err)
+		}
+		otlpGrpcEndpoint = fmt.Sprintf("localhost:%s", grpcPort.Port())
+		otlpHttpEndpoint, err = collectorC.endpoint(ctx, otlpHttpPort)
+		if err != nil {
+			return fmt.Errorf("error get otlp http endpoint: %w", err)
+		}
+	}
 
-	grabanaClient := NewGrafanaAddOn(grafanaUrl, "admin", "admin")
+	alertmanagerUrl := ""
+	if c.Bool(CliDevAlertmanager) {
+		webhook, webhookPort, err := startAlertWebhook()
+		if err != nil {
+			return err
+		}
+		defer webhook.Close()
+		alertmanagerC, err := startAlertmanager(ctx, stack, settings.images["alertmanager"], fmt.Sprintf("http://%s:%d/", devHost(c), webhookPort))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if detached {
+				return
+			}
+			if err := alertmanagerC.Terminate(ctx); err != nil {
+				panic(err)
+			}
+		}()
+		if err := addAlertmanagerDatasource(client, alertmanagerC); err != nil {
+			return err
+		}
+		alertmanagerUrl, err = alertmanagerC.endpoint(ctx, alertmanagerPort)
+		if err != nil {
+			return fmt.Errorf("error get alertmanager endpoint: %w", err)
+		}
+		if config, err := os.ReadFile(devPrometheusConfigFile); err == nil && !strings.Contains(string(config), "alertmanager:9093") {
+			fmt.Printf("WARNING: %s has no alerting section for alertmanager:9093, prometheus will not send alerts (use --%s to regenerate it)\n", devPrometheusConfigFile, CliDevForce)
+		}
+	}
 
-	apiKey, err := grabanaClient.CreateAPIKey("debug", "test")
+	grabanaClient := NewGrafanaAddOn(grafanaUrl, settings.adminUser, settings.adminPassword)
+
+	serviceAccount := "debug"
+	if persist {
+		// the service account of an earlier run still exists
+		serviceAccount = "debug-" + uuid.New().String()[:8]
+	}
+	apiKey, err := grabanaClient.CreateAPIKey(serviceAccount, "test")
 
 	if err != nil {
 		return fmt.Errorf("error create grafana apikey: %w", err)
 	}
 
+	if !c.Bool(CliDevNoApply) && (r.Dashboard != nil || r.FolderDashboards != nil) {
+		r.cfg = cfg
+		r.client = client
+		if err := r.Apply(ctx, c); err != nil {
+			fmt.Printf("auto apply of the dashboards failed: %s\n", err)
+		}
+	}
+
 	fmt.Printf("Prometheus endpoint: %s \n", prometheusUrl)
 	fmt.Printf("\tReload Config: curl -s -XPOST %s/-/reload\n ", prometheusUrl)
 	fmt.Printf("Grafana endpoint: %s \n", grafanaUrl)
-	fmt.Printf("\tGrafana user: admin \n")
-	fmt.Printf("\tGrafana password: admin \n")
+	fmt.Printf("\tGrafana user: %s \n", settings.adminUser)
+	fmt.Printf("\tGrafana password: %s \n", settings.adminPassword)
 	fmt.Printf("\tPrometheus Datasourcename: %s\n", c.String(CliDevDatasourceName))
 	fmt.Printf("\tApi key: %s \n", apiKey)
+	if lokiPushUrl != "" {
+		fmt.Printf("Loki push endpoint: %s \n", lokiPushUrl)
+		fmt.Printf("\tLoki Datasourceuid: %s\n", DevLokiDatasourceUID)
+	}
+	if testData {
+		fmt.Printf("TestData Datasourceuid: %s\n", DevTestDataDatasourceUID)
+		if scenario != "" {
+			fmt.Printf("\tPrometheus queries are swapped for the %s scenario\n", scenario)
+		}
+	}
+	if otlpHttpEndpoint != "" {
+		fmt.Printf("OTLP endpoint (otel collector): grpc %s http %s \n", otlpGrpcEndpoint, otlpHttpEndpoint)
+		fmt.Printf("\tOTEL_EXPORTER_OTLP_ENDPOINT=%s\n", otlpHttpEndpoint)
+		fmt.Printf("\tTempo Datasourceuid: %s\n", DevTempoDatasourceUID)
+	}
+	if alertmanagerUrl != "" {
+		fmt.Printf("Alertmanager endpoint: %s \n", alertmanagerUrl)
+		fmt.Printf("\tNotifications of firing and resolved alerts are printed here\n")
+	}
 	fmt.Printf("Simple run\n go run . dashboard apply --server %s --apikey %s \n", grafanaUrl, apiKey)
+	if detach {
+		err := writeDevState(DevState{
+			App:              r.appName,
+			Network:          newNetwork.Name,
+			StartedAt:        time.Now().UTC(),
+			GrafanaUrl:       grafanaUrl,
+			ApiKey:           apiKey,
+			PrometheusUrl:    prometheusUrl,
+			Datasource:       c.String(CliDevDatasourceName),
+			LokiPushUrl:      lokiPushUrl,
+			OtlpGrpcEndpoint: otlpGrpcEndpoint,
+			OtlpHttpEndpoint: otlpHttpEndpoint,
+			AlertmanagerUrl:  alertmanagerUrl,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to write dev state: %w", err)
+		}
+		detached = true
+		fmt.Printf("Dev stack detached (state at %s), use dev status, dev env and dev stop\n", devStateFile)
+		return nil
+	}
+	if c.Bool(CliDevWatch) {
+		return watchDashboards(ctx, done, grafanaUrl, apiKey, devApplyArgs(c, scenario), func(bin string) error {
+			return r.reloadDevScrapeTargets(ctx, c, bin, prometheusUrl)
+		})
+	}
 	<-done
 	return nil
 }
@@ -0,0 +1,210 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/grafana/grafana-openapi-client-go/client/datasources"
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/urfave/cli/v3"
+)
+
+// Datasource is upserted by its UID. Secure json fields are never part of the code, they are read from
+// env vars or files at apply time.
+type Datasource struct {
+	models.AddDataSourceCommand
+	// SecureValues maps a secure json field (for example basicAuthPassword) to the source of its value
+	SecureValues map[string]SecureValue
+}
+
+// SecureValue is read from the env var Env or the file File
+type SecureValue struct {
+	Env  string
+	File string
+}
+
+func SecureEnv(name string) SecureValue {
+	return SecureValue{Env: name}
+}
+
+func SecureFile(path string) SecureValue {
+	return SecureValue{File: path}
+}
+
+func (s SecureValue) resolve() (string, error) {
+	switch {
+	case s.Env != "":
+		v, ok := os.LookupEnv(s.Env)
+		if !ok {
+			return "", fmt.Errorf("env %s is not set", s.Env)
+		}
+		return v, nil
+	case s.File != "":
+		b, err := os.ReadFile(s.File)
+		if err != nil {
+			return "", fmt.Errorf("unable to read secure value: %w", err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	}
+	return "", fmt.Errorf("secure value needs an env or a file")
+}
+
+func (r *Runner) DatasourceApply(ctx context.Context, c *cli.Command) error {
+	ds, err := r.getDatasources(c)
+	if err != nil {
+		return fmt.Errorf("failed apply datasources: %w", err)
+	}
+	for _, d := range ds {
+		secure := make(map[string]string, len(d.SecureValues))
+		for k, v := range d.SecureValues {
+			value, err := v.resolve()
+			if err != nil {
+				return fmt.Errorf("datasource %s secure field %s: %w", d.UID, k, err)
+			}
+			secure[k] = value
+		}
+		cmd := d.AddDataSourceCommand
+		cmd.SecureJSONData = secure
+
+		_, found, err := r.getRemoteDatasource(d.UID)
+		if err != nil {
+			return err
+		}
+		if found {
+			var update models.UpdateDataSourceCommand
+			if err := convertJSON(cmd, &update); err != nil {
+				return err
+			}
+			_, err = r.client.Datasources.UpdateDataSourceByUID(d.UID, &update)
+		} else {
+			_, err = r.client.Datasources.AddDataSource(&cmd)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to apply datasource %s: %w", d.UID, err)
+		}
+		fmt.Printf("datasource %s (%s): applied\n", d.Name, d.UID)
+	}
+	fmt.Println("Datasources applied")
+	return nil
+}
+
+func (r *Runner) DatasourcePlan(ctx context.Context, c *cli.Command) error {
+	detailed := c.Bool(CliDetailedExitCode)
+	ds, err := r.getDatasources(c)
+	if err != nil {
+		return planError(detailed, fmt.Errorf("failed plan datasources: %w", err))
+	}
+	var result PlanResult
+	for _, d := range ds {
+		change := ResourceChange{Kind: "datasource", Uid: d.UID, Title: d.Name}
+		remote, found, err := r.getRemoteDatasource(d.UID)
+		if err != nil {
+			return planError(detailed, err)
+		}
+		if !found {
+			change.Action = PlanCreate
+			result.Changes = append(result.Changes, change)
+			continue
+		}
+		server, err := normalizeDatasource(remote, remote.SecureJSONFields)
+		if err != nil {
+			return planError(detailed, err)
+		}
+		fields := make(map[string]bool, len(d.SecureValues))
+		for k := range d.SecureValues {
+			fields[k] = true
+		}
+		local, err := normalizeDatasource(d.AddDataSourceCommand, fields)
+		if err != nil {
+			return planError(detailed, err)
+		}
+		change.Diffs = diffValues("", server, local)
+		change.Action = PlanUnchanged
+		if len(change.Diffs) > 0 {
+			change.Action = PlanUpdate
+		}
+		result.Changes = append(result.Changes, change)
+	}
+	return finishPlan(detailed, result)
+}
+
+func (r *Runner) DatasourceDestroy(ctx context.Context, c *cli.Command) error {
+	ds, err := r.getDatasources(c)
+	if err != nil {
+		return fmt.Errorf("failed destroy datasources: %w", err)
+	}
+	errList := errors.Join(nil)
+	for _, d := range ds {
+		_, err := r.client.Datasources.DeleteDataSourceByUID(d.UID)
+		if err != nil {
+			var notFound *datasources.DeleteDataSourceByUIDNotFound
+			if !errors.As(err, &notFound) {
+				errList = errors.Join(errList, fmt.Errorf("unable to delete datasource %s: %w", d.UID, err))
+			}
+		}
+	}
+	if errList != nil {
+		return errList
+	}
+	fmt.Println("Destroyed")
+	return nil
+}
+
+func (r *Runner) getDatasources(c *cli.Command) ([]Datasource, error) {
+	if r.Datasources == nil {
+		return nil, fmt.Errorf("no DatasourceCreator set, use DatasourceBuilder option")
+	}
+	ds, err := r.Datasources(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed get datasources %w", err)
+	}
+	for i, d := range ds {
+		if d.UID == "" {
+			return nil, fmt.Errorf("datasource %s needs an uid", d.Name)
+		}
+		if d.Access == "" {
+			ds[i].Access = "proxy"
+		}
+	}
+	return ds, nil
+}
+
+// getRemoteDatasource returns the datasource with the given uid. If it does not exist found is false.
+func (r *Runner) getRemoteDatasource(uid string) (*models.DataSource, bool, error) {
+	d, err := r.client.Datasources.GetDataSourceByUID(uid)
+	if err != nil {
+		var notFound *datasources.GetDataSourceByUIDNotFound
+		if errors.As(err, &notFound) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("unable to get datasource %s: %w", uid, err)
+	}
+	return d.Payload, true, nil
+}
+
+// normalizeDatasource keeps only the fields the code manages. Secure values are never returned by grafana,
+// so only the names of the set secure fields are compared.
+func normalizeDatasource(d any, secureFields map[string]bool) (map[string]any, error) {
+	var cmd models.AddDataSourceCommand
+	if err := convertJSON(d, &cmd); err != nil {
+		return nil, err
+	}
+	cmd.SecureJSONData = nil
+	var res map[string]any
+	if err := convertJSON(cmd, &res); err != nil {
+		return nil, err
+	}
+	var fields []any
+	for _, k := range sortedKeys(secureFields) {
+		if secureFields[k] {
+			fields = append(fields, k)
+		}
+	}
+	if len(fields) > 0 {
+		res["secureJsonFields"] = fields
+	}
+	return res, nil
+}
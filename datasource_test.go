@@ -0,0 +1,62 @@
+package grafanasdkclistarter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+)
+
+func TestNormalizeDatasource(t *testing.T) {
+	tests := []struct {
+		name   string
+		remote string
+		local  string
+		secure map[string]SecureValue
+		want   []string
+	}{
+		{
+			name:   "grafana managed fields and secure values",
+			remote: `{"id":4,"uid":"prom","orgId":1,"version":3,"readOnly":false,"typeLogoUrl":"public/prom.svg","name":"Prometheus","type":"prometheus","access":"proxy","url":"http://prom:9090","basicAuth":true,"secureJsonFields":{"basicAuthPassword":true,"tlsClientKey":false}}`,
+			local:  `{"uid":"prom","name":"Prometheus","type":"prometheus","access":"proxy","url":"http://prom:9090","basicAuth":true,"secureJsonData":{"basicAuthPassword":"secret"}}`,
+			secure: map[string]SecureValue{"basicAuthPassword": SecureEnv("PROM_PASSWORD")},
+		},
+		{
+			name:   "edited url",
+			remote: `{"id":4,"uid":"prom","version":3,"name":"Prometheus","type":"prometheus","url":"http://prom:9090"}`,
+			local:  `{"uid":"prom","name":"Prometheus","type":"prometheus","url":"http://other:9090"}`,
+			want:   []string{`~ url: "http://prom:9090" => "http://other:9090"`},
+		},
+		{
+			name:   "new secure field",
+			remote: `{"id":4,"uid":"prom","version":3,"name":"Prometheus","type":"prometheus","secureJsonFields":{"basicAuthPassword":true}}`,
+			local:  `{"uid":"prom","name":"Prometheus","type":"prometheus"}`,
+			secure: map[string]SecureValue{"basicAuthPassword": SecureEnv("PROM_PASSWORD"), "httpHeaderValue1": SecureFile("token")},
+			want:   []string{`+ secureJsonFields[1]: "httpHeaderValue1"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := jsonModel[models.DataSource](t, tt.remote)
+			server, err := normalizeDatasource(remote, remote.SecureJSONFields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fields := make(map[string]bool, len(tt.secure))
+			for k := range tt.secure {
+				fields[k] = true
+			}
+			local, err := normalizeDatasource(jsonModel[models.AddDataSourceCommand](t, tt.local), fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, d := range diffValues("", server, local) {
+				got = append(got, d.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diff = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
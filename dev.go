@@ -0,0 +1,287 @@
+package grafanasdkclistarter
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/google/uuid"
+	goapi "github.com/grafana/grafana-openapi-client-go/client"
+	"github.com/grafana/grafana-openapi-client-go/client/datasources"
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	// DevLokiDatasourceUID is the uid of the loki datasource of the dev stack
+	DevLokiDatasourceUID = "loki"
+	// DevTempoDatasourceUID is the uid of the tempo datasource of the dev stack
+	DevTempoDatasourceUID = "tempo"
+	lokiPort              = "3100/tcp"
+	tempoPort             = "3200/tcp"
+	otlpGrpcPort          = "4317/tcp"
+	otlpHttpPort          = "4318/tcp"
+	alertmanagerPort      = "9093/tcp"
+	// DevAlertmanagerDatasourceUID is the uid of the alertmanager datasource of the dev stack
+	DevAlertmanagerDatasourceUID = "alertmanager"
+	// prometheusAlias is the name of the dev prometheus inside the dev network
+	prometheusAlias = "prometheus"
+)
+
+//go:embed tempo.yaml
+var tempoConfig []byte
+
+//go:embed otel-collector.yaml
+var otelCollectorConfig []byte
+
+// devContainer is an optional container of the dev stack, reachable by its name inside the dev network
+type devContainer struct {
+	name      string
+	container testcontainers.Container
+}
+
+func (d devContainer) internalUrl(port string) string {
+	return fmt.Sprintf("http://%s:%s", d.name, nat.Port(port).Port())
+}
+
+func (d devContainer) endpoint(ctx context.Context, port string) (string, error) {
+	return d.container.PortEndpoint(ctx, nat.Port(port), "http")
+}
+
+func (d devContainer) Terminate(ctx context.Context) error {
+	return d.container.Terminate(ctx)
+}
+
+// startDevContainer starts the request inside the dev network. The container name gets an uuid suffix,
+// inside the network it is reachable by name.
+func startDevContainer(ctx context.Context, stack devStack, name string, req testcontainers.ContainerRequest) (devContainer, error) {
+	req.Name = name + "_" + uuid.New().String()
+	req.Networks = []string{stack.network}
+	req.NetworkAliases = map[string][]string{stack.network: {name}}
+	req.Labels = stack.labels(name)
+	c, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	if err != nil {
+		return devContainer{}, fmt.Errorf("error start %s: %w", name, err)
+	}
+	return devContainer{name: name, container: c}, nil
+}
+
+func startLoki(ctx context.Context, stack devStack, image string) (devContainer, error) {
+	return startDevContainer(ctx, stack, "loki", testcontainers.ContainerRequest{
+		Image:        image,
+		ExposedPorts: []string{lokiPort},
+		WaitingFor:   wait.ForHTTP("/ready").WithPort(nat.Port(lokiPort)),
+	})
+}
+
+func addLokiDatasource(client *goapi.GrafanaHTTPAPI, loki devContainer) error {
+	err := upsertDevDatasource(client, &models.AddDataSourceCommand{
+		Name:   "Loki",
+		URL:    loki.internalUrl(lokiPort),
+		UID:    DevLokiDatasourceUID,
+		Type:   "loki",
+		Access: "proxy",
+	})
+	if err != nil {
+		return fmt.Errorf("error create loki datasource at grafana: %w", err)
+	}
+	return nil
+}
+
+// startTracing starts tempo and an otel collector sending traces to tempo and metrics to the dev prometheus
+func startTracing(ctx context.Context, stack devStack, tempoImage, collectorImage string) (tempo devContainer, collector devContainer, err error) {
+	tempo, err = startDevContainer(ctx, stack, "tempo", testcontainers.ContainerRequest{
+		Image:        tempoImage,
+		ExposedPorts: []string{tempoPort},
+		Cmd:          []string{"-config.file=/etc/tempo.yaml"},
+		Files: []testcontainers.ContainerFile{
+			{Reader: bytes.NewReader(tempoConfig), ContainerFilePath: "/etc/tempo.yaml", FileMode: 0644},
+		},
+		Tmpfs:      map[string]string{"/var/tempo": "mode=1777"},
+		WaitingFor: wait.ForListeningPort(nat.Port(tempoPort)),
+	})
+	if err != nil {
+		return tempo, collector, err
+	}
+	collector, err = startDevContainer(ctx, stack, "otel-collector", testcontainers.ContainerRequest{
+		Image:        collectorImage,
+		ExposedPorts: []string{otlpGrpcPort, otlpHttpPort},
+		Files: []testcontainers.ContainerFile{
+			{Reader: bytes.NewReader(otelCollectorConfig), ContainerFilePath: "/etc/otelcol-contrib/config.yaml", FileMode: 0644},
+		},
+		WaitingFor: wait.ForListeningPort(nat.Port(otlpHttpPort)),
+	})
+	if err != nil {
+		if termErr := tempo.Terminate(ctx); termErr != nil {
+			panic(termErr)
+		}
+	}
+	return tempo, collector, err
+}
+
+// prometheusExemplarJSONData links the trace ids of prometheus exemplars to tempo
+func prometheusExemplarJSONData() map[string]any {
+	return map[string]any{
+		"exemplarTraceIdDestinations": []map[string]any{
+			{"name": "trace_id", "datasourceUid": DevTempoDatasourceUID},
+		},
+	}
+}
+
+// addTempoDatasource adds tempo with trace to metrics and the service map based on the span metrics inside prometheus
+func addTempoDatasource(client *goapi.GrafanaHTTPAPI, tempo devContainer, prometheusUID string, withLoki bool) error {
+	jsonData := map[string]any{
+		"tracesToMetrics": map[string]any{
+			"datasourceUid":      prometheusUID,
+			"spanStartTimeShift": "-2m",
+			"spanEndTimeShift":   "2m",
+			"tags":               []map[string]any{{"key": "service.name", "value": "service"}},
+			"queries": []map[string]any{
+				{"name": "Request rate", "query": "sum(rate(traces_spanmetrics_calls_total{$__tags}[5m]))"},
+				{"name": "Error rate", "query": "sum(rate(traces_spanmetrics_calls_total{$__tags,status_code=\"STATUS_CODE_ERROR\"}[5m]))"},
+			},
+		},
+		"serviceMap": map[string]any{"datasourceUid": prometheusUID},
+		"nodeGraph":  map[string]any{"enabled": true},
+	}
+	if withLoki {
+		jsonData["tracesToLogsV2"] = map[string]any{"datasourceUid": DevLokiDatasourceUID, "filterByTraceID": true}
+	}
+	err := upsertDevDatasource(client, &models.AddDataSourceCommand{
+		Name:     "Tempo",
+		URL:      tempo.internalUrl(tempoPort),
+		UID:      DevTempoDatasourceUID,
+		Type:     "tempo",
+		Access:   "proxy",
+		JSONData: jsonData,
+	})
+	if err != nil {
+		return fmt.Errorf("error create tempo datasource at grafana: %w", err)
+	}
+	return nil
+}
+
+// alertmanagerConfig routes all alerts to the console webhook of the cli
+const alertmanagerConfig = `route:
+  receiver: console
+  group_by: ['alertname']
+  group_wait: 5s
+  group_interval: 10s
+  repeat_interval: 1h
+receivers:
+  - name: console
+    webhook_configs:
+      - url: %s
+        send_resolved: true
+`
+
+// webhookMessage is the part of the alertmanager webhook payload printed to the console
+type webhookMessage struct {
+	Status string `json:"status"`
+	Alerts []struct {
+		Status      string            `json:"status"`
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+		StartsAt    time.Time         `json:"startsAt"`
+		EndsAt      time.Time         `json:"endsAt"`
+	} `json:"alerts"`
+}
+
+// startAlertWebhook listens on all interfaces of the host, so the alertmanager container reaches it by the dev host address
+func startAlertWebhook() (*http.Server, int, error) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to listen for alert webhook: %w", err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(printAlerts)}
+	go func() {
+		if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			fmt.Printf("alert webhook stopped: %s\n", err)
+		}
+	}()
+	return srv, l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func printAlerts(w http.ResponseWriter, req *http.Request) {
+	var msg webhookMessage
+	if err := json.NewDecoder(req.Body).Decode(&msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, a := range msg.Alerts {
+		var labels []string
+		for _, k := range sortedKeys(a.Labels) {
+			if k != "alertname" {
+				labels = append(labels, fmt.Sprintf("%s=%q", k, a.Labels[k]))
+			}
+		}
+		since := a.StartsAt
+		if a.Status == "resolved" {
+			since = a.EndsAt
+		}
+		fmt.Printf("[%s] %s {%s} since %s\n", strings.ToUpper(a.Status), a.Labels["alertname"], strings.Join(labels, ", "), since.Local().Format(time.TimeOnly))
+		for _, k := range sortedKeys(a.Annotations) {
+			fmt.Printf("\t%s: %s\n", k, a.Annotations[k])
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// startAlertmanager starts alertmanager sending all notifications to webhookUrl
+func startAlertmanager(ctx context.Context, stack devStack, image, webhookUrl string) (devContainer, error) {
+	return startDevContainer(ctx, stack, "alertmanager", testcontainers.ContainerRequest{
+		Image:        image,
+		ExposedPorts: []string{alertmanagerPort},
+		Files: []testcontainers.ContainerFile{
+			{Reader: strings.NewReader(fmt.Sprintf(alertmanagerConfig, webhookUrl)), ContainerFilePath: "/etc/alertmanager/alertmanager.yml", FileMode: 0644},
+		},
+		HostConfigModifier: withHostGateway,
+		WaitingFor:         wait.ForHTTP("/-/ready").WithPort(nat.Port(alertmanagerPort)),
+	})
+}
+
+func addAlertmanagerDatasource(client *goapi.GrafanaHTTPAPI, alertmanager devContainer) error {
+	err := upsertDevDatasource(client, &models.AddDataSourceCommand{
+		Name:     "Alertmanager",
+		URL:      alertmanager.internalUrl(alertmanagerPort),
+		UID:      DevAlertmanagerDatasourceUID,
+		Type:     "alertmanager",
+		Access:   "proxy",
+		JSONData: map[string]any{"implementation": "prometheus"},
+	})
+	if err != nil {
+		return fmt.Errorf("error create alertmanager datasource at grafana: %w", err)
+	}
+	return nil
+}
+
+// upsertDevDatasource adds the datasource or updates it, if grafana keeps its data across dev runs
+func upsertDevDatasource(client *goapi.GrafanaHTTPAPI, cmd *models.AddDataSourceCommand) error {
+	_, err := client.Datasources.GetDataSourceByUID(cmd.UID)
+	if err != nil {
+		var notFound *datasources.GetDataSourceByUIDNotFound
+		if !errors.As(err, &notFound) {
+			return err
+		}
+		_, err = client.Datasources.AddDataSource(cmd)
+		return err
+	}
+	var update models.UpdateDataSourceCommand
+	if err := convertJSON(cmd, &update); err != nil {
+		return err
+	}
+	_, err = client.Datasources.UpdateDataSourceByUID(cmd.UID, &update)
+	return err
+}
@@ -0,0 +1,185 @@
+package grafanasdkclistarter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+	"text/template"
+
+	"github.com/urfave/cli/v3"
+)
+
+const (
+	devPrometheusConfigFile = "./prometheus/prometheus.yml"
+	devRecordRulesFile      = "./prometheus/record_rules.yaml"
+)
+
+// ScrapeTarget is a job of the dev prometheus scraping your app running at the host
+type ScrapeTarget struct {
+	Job string
+	// Ports of your app at the host, every port is a target of the job
+	Ports       []int
+	MetricsPath string
+	Scheme      string
+}
+
+// devPrometheusConfig is the data of prometheus.yml.tmpl
+type devPrometheusConfig struct {
+	// Host is the address the containers reach the host with
+	Host    string
+	Targets []ScrapeTarget
+	// Alertmanager renders the alerting section for the alertmanager of dev run
+	Alertmanager bool
+}
+
+// DevScrapeTarget adds a target of the dev prometheus. dev init and dev run render the prometheus config from
+// these targets and the scrape flags. Targets of the same job are merged.
+func DevScrapeTarget(job string, port int, path string) Option {
+	return func(runner *Runner, app *cli.Command) error {
+		if job == "" || port <= 0 {
+			return fmt.Errorf("dev scrape target needs a job and a port")
+		}
+		runner.devScrapeTargets = append(runner.devScrapeTargets, ScrapeTarget{Job: job, Ports: []int{port}, MetricsPath: path})
+		return nil
+	}
+}
+
+// devPrometheusConfigOf returns the config of the scrape flags and the given targets
+func devPrometheusConfigOf(c *cli.Command, targets []ScrapeTarget) (devPrometheusConfig, error) {
+	config := devPrometheusConfig{Host: devHost(c), Alertmanager: c.Bool(CliDevAlertmanager)}
+	if ports := c.IntSlice(CliDevScrapePort); len(ports) > 0 {
+		target := ScrapeTarget{
+			Job:         c.String(CliDevJob),
+			MetricsPath: c.String(CliDevMetricsPath),
+			Scheme:      c.String(CliDevScheme),
+		}
+		for _, p := range ports {
+			target.Ports = append(target.Ports, int(p))
+		}
+		targets = append([]ScrapeTarget{target}, targets...)
+	}
+	jobs := map[string]int{}
+	for _, t := range targets {
+		if t.MetricsPath == "" {
+			t.MetricsPath = "/metrics"
+		}
+		if t.Scheme == "" {
+			t.Scheme = "http"
+		}
+		i, ok := jobs[t.Job]
+		if !ok {
+			jobs[t.Job] = len(config.Targets)
+			config.Targets = append(config.Targets, t)
+			continue
+		}
+		existing := &config.Targets[i]
+		if existing.MetricsPath != t.MetricsPath || existing.Scheme != t.Scheme {
+			return config, fmt.Errorf("dev scrape targets of job %s need the same metrics path and scheme", t.Job)
+		}
+		existing.Ports = append(existing.Ports, t.Ports...)
+	}
+	return config, nil
+}
+
+func renderPrometheusConfig(config devPrometheusConfig) ([]byte, error) {
+	for _, t := range config.Targets {
+		if t.Job == "" || len(t.Ports) == 0 {
+			return nil, fmt.Errorf("dev scrape target needs a job and at least one port: %+v", t)
+		}
+	}
+	tmpl, err := template.New("prometheus").Funcs(template.FuncMap{"quote": yamlQuote}).Parse(prometheusTmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prometheus template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, config); err != nil {
+		return nil, fmt.Errorf("unable to render prometheus config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// yamlQuote returns s as double quoted yaml scalar, json strings are valid yaml
+func yamlQuote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// PrintDevScrapeTargets prints the targets of the DevScrapeTarget options, watch mode reads them from the rebuilt app
+func (r *Runner) PrintDevScrapeTargets(ctx context.Context, c *cli.Command) error {
+	return json.NewEncoder(os.Stdout).Encode(r.devScrapeTargets)
+}
+
+// reloadDevScrapeTargets renders the prometheus config with the targets of the rebuilt app at bin.
+// If the config changes prometheus reloads it by its lifecycle endpoint.
+func (r *Runner) reloadDevScrapeTargets(ctx context.Context, c *cli.Command, bin, prometheusUrl string) error {
+	out, err := exec.CommandContext(ctx, bin, "dev", "targets").Output()
+	if err != nil {
+		return fmt.Errorf("unable to get dev scrape targets: %w", err)
+	}
+	var targets []ScrapeTarget
+	if err := json.Unmarshal(out, &targets); err != nil {
+		return fmt.Errorf("unable to read dev scrape targets: %w", err)
+	}
+	// a config without DevScrapeTarget options is maintained by hand
+	if len(targets) == 0 && len(r.devScrapeTargets) == 0 {
+		return nil
+	}
+	previous, err := r.renderDevPrometheusConfig(c)
+	if err != nil {
+		return err
+	}
+	r.devScrapeTargets = targets
+	rendered, err := r.renderDevPrometheusConfig(c)
+	if err != nil {
+		return err
+	}
+	current, err := os.ReadFile(devPrometheusConfigFile)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(current, rendered) {
+		return nil
+	}
+	// edits by hand are kept
+	if !bytes.Equal(current, previous) {
+		fmt.Printf("dev scrape targets changed, %s is edited by hand and not updated (use dev init --%s)\n", devPrometheusConfigFile, CliDevForce)
+		return nil
+	}
+	if err := os.WriteFile(devPrometheusConfigFile, rendered, os.ModePerm); err != nil {
+		return err
+	}
+	res, err := http.Post(prometheusUrl+"/-/reload", "", nil)
+	if err != nil {
+		return fmt.Errorf("unable to reload prometheus: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to reload prometheus: %s", res.Status)
+	}
+	fmt.Println("dev scrape targets changed, prometheus reloaded")
+	return nil
+}
+
+func (r *Runner) renderDevPrometheusConfig(c *cli.Command) ([]byte, error) {
+	config, err := r.devPrometheusConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	return renderPrometheusConfig(config)
+}
+
+func (r *Runner) devPrometheusConfig(c *cli.Command) (devPrometheusConfig, error) {
+	synthetic, err := r.syntheticScrapeTarget(c)
+	if err != nil {
+		return devPrometheusConfig{}, err
+	}
+	if synthetic == nil {
+		return devPrometheusConfigOf(c, r.devScrapeTargets)
+	}
+	return devPrometheusConfigOf(c, append(slices.Clone(r.devScrapeTargets), *synthetic))
+}
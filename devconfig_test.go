@@ -0,0 +1,101 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRenderPrometheusConfigAlerting(t *testing.T) {
+	tests := []struct {
+		name         string
+		alertmanager bool
+	}{
+		{name: "without alertmanager", alertmanager: false},
+		{name: "with alertmanager", alertmanager: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := renderPrometheusConfig(devPrometheusConfig{Host: "host.docker.internal", Alertmanager: tt.alertmanager})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Contains(string(config), "alertmanager:9093"); got != tt.alertmanager {
+				t.Errorf("alerting section rendered = %t, want %t:\n%s", got, tt.alertmanager, config)
+			}
+		})
+	}
+}
+
+func TestRenderPrometheusConfigQuotesValues(t *testing.T) {
+	target := ScrapeTarget{Job: `it's "my" job: #1`, Ports: []int{8080, 8081}, MetricsPath: "/metrics?format='text'", Scheme: "http"}
+	config, err := renderPrometheusConfig(devPrometheusConfig{Host: "host.docker.internal", Targets: []ScrapeTarget{target}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed struct {
+		ScrapeConfigs []struct {
+			JobName       string `yaml:"job_name"`
+			Scheme        string `yaml:"scheme"`
+			MetricsPath   string `yaml:"metrics_path"`
+			StaticConfigs []struct {
+				Targets []string `yaml:"targets"`
+			} `yaml:"static_configs"`
+		} `yaml:"scrape_configs"`
+	}
+	if err := yaml.Unmarshal(config, &parsed); err != nil {
+		t.Fatalf("rendered config is no valid yaml: %s\n%s", err, config)
+	}
+	if len(parsed.ScrapeConfigs) != 2 {
+		t.Fatalf("got %d scrape configs, want 2:\n%s", len(parsed.ScrapeConfigs), config)
+	}
+	got := parsed.ScrapeConfigs[1]
+	if got.JobName != target.Job || got.MetricsPath != target.MetricsPath || got.Scheme != target.Scheme {
+		t.Errorf("scrape config = %+v, want job %q path %q", got, target.Job, target.MetricsPath)
+	}
+	if want := []string{"host.docker.internal:8080", "host.docker.internal:8081"}; strings.Join(got.StaticConfigs[0].Targets, ",") != strings.Join(want, ",") {
+		t.Errorf("targets = %q, want %q", got.StaticConfigs[0].Targets, want)
+	}
+}
+
+func TestInitDevKeepsEditedConfig(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(pwd) })
+
+	r := &Runner{devScrapeTargets: []ScrapeTarget{{Job: "app", Ports: []int{8080}}}}
+	initDev := func(args ...string) string {
+		t.Helper()
+		cmd := &cli.Command{Name: "init", Flags: []cli.Flag{&cli.BoolFlag{Name: CliDevForce}}, Action: r.InitDev}
+		if err := cmd.Run(context.Background(), append([]string{"init"}, args...)); err != nil {
+			t.Fatal(err)
+		}
+		config, err := os.ReadFile(devPrometheusConfigFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(config)
+	}
+
+	if config := initDev(); !strings.Contains(config, `job_name: "app"`) {
+		t.Fatalf("missing config is not rendered from the targets:\n%s", config)
+	}
+	if err := os.WriteFile(devPrometheusConfigFile, []byte("# edited\n"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if config := initDev(); config != "# edited\n" {
+		t.Errorf("edited config is overwritten without --force:\n%s", config)
+	}
+	if config := initDev("--force"); !strings.Contains(config, `job_name: "app"`) {
+		t.Errorf("config is not regenerated with --force:\n%s", config)
+	}
+}
@@ -0,0 +1,128 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/urfave/cli/v3"
+)
+
+const (
+	// DevHostAlias is the name the dev containers reach the host with. On linux it is mapped to the docker host gateway.
+	DevHostAlias = "host.docker.internal"
+	// devSubnetBits is the prefix length of the detected dev network
+	devSubnetBits = 24
+)
+
+// devSubnetPools are searched in order for a subnet of the dev network
+var devSubnetPools = []netip.Prefix{
+	netip.MustParsePrefix("172.28.0.0/14"),
+	netip.MustParsePrefix("10.199.0.0/16"),
+	netip.MustParsePrefix("192.168.192.0/18"),
+}
+
+// devHost returns the address of the host used inside the rendered configs
+func devHost(c *cli.Command) string {
+	if h := c.String(CliDevHost); h != "" {
+		return h
+	}
+	return DevHostAlias
+}
+
+// withHostGateway maps DevHostAlias to the host, docker desktop knows the name already
+func withHostGateway(hc *container.HostConfig) {
+	hc.ExtraHosts = append(hc.ExtraHosts, DevHostAlias+":host-gateway")
+}
+
+// devSubnet returns the subnet and gateway of the flags. Missing values are detected.
+func devSubnet(ctx context.Context, c *cli.Command) (subnet string, gateway string, err error) {
+	subnet, gateway = c.String(CliDevSubnet), c.String(CliDevGateway)
+	if subnet == "" {
+		p, err := freeDevSubnet(ctx)
+		if err != nil {
+			return "", "", err
+		}
+		subnet = p.String()
+	}
+	if gateway == "" {
+		p, err := netip.ParsePrefix(subnet)
+		if err != nil {
+			return "", "", fmt.Errorf("%s is not a valid subnet: %w", subnet, err)
+		}
+		gateway = p.Masked().Addr().Next().String()
+	}
+	return subnet, gateway, nil
+}
+
+// freeDevSubnet returns the first subnet of the pools which overlaps neither a docker network nor a network of the host (like a vpn)
+func freeDevSubnet(ctx context.Context) (netip.Prefix, error) {
+	used, err := usedSubnets(ctx)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return firstFreeSubnet(devSubnetPools, devSubnetBits, used)
+}
+
+// firstFreeSubnet returns the first subnet with the given prefix length inside the pools which overlaps none of the used subnets
+func firstFreeSubnet(pools []netip.Prefix, bits int, used []netip.Prefix) (netip.Prefix, error) {
+	for _, pool := range pools {
+		for p := netip.PrefixFrom(pool.Addr(), bits); pool.Contains(p.Addr()); p = nextPrefix(p) {
+			if !overlapsAny(p, used) {
+				return p, nil
+			}
+		}
+	}
+	return netip.Prefix{}, fmt.Errorf("no free subnet for the dev network found, use --%s and --%s", CliDevSubnet, CliDevGateway)
+}
+
+func nextPrefix(p netip.Prefix) netip.Prefix {
+	a := p.Addr().As4()
+	n := uint32(a[0])<<24 | uint32(a[1])<<16 | uint32(a[2])<<8 | uint32(a[3])
+	n += 1 << (32 - p.Bits())
+	return netip.PrefixFrom(netip.AddrFrom4([4]byte{byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}), p.Bits())
+}
+
+func overlapsAny(p netip.Prefix, used []netip.Prefix) bool {
+	for _, u := range used {
+		if p.Overlaps(u) {
+			return true
+		}
+	}
+	return false
+}
+
+// usedSubnets returns the subnets of all docker networks and host interfaces
+func usedSubnets(ctx context.Context) ([]netip.Prefix, error) {
+	var res []netip.Prefix
+	docker, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to docker: %w", err)
+	}
+	defer docker.Close()
+	networks, err := docker.NetworkList(ctx, network.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list docker networks: %w", err)
+	}
+	for _, n := range networks {
+		for _, cfg := range n.IPAM.Config {
+			if p, err := netip.ParsePrefix(cfg.Subnet); err == nil {
+				res = append(res, p.Masked())
+			}
+		}
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list host networks: %w", err)
+	}
+	for _, a := range addrs {
+		if p, err := netip.ParsePrefix(a.String()); err == nil && p.Addr().Is4() {
+			res = append(res, p.Masked())
+		}
+	}
+	return res, nil
+}
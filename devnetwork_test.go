@@ -0,0 +1,84 @@
+package grafanasdkclistarter
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func prefixes(s ...string) []netip.Prefix {
+	var res []netip.Prefix
+	for _, p := range s {
+		res = append(res, netip.MustParsePrefix(p))
+	}
+	return res
+}
+
+func TestNextPrefix(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{prefix: "172.28.0.0/24", want: "172.28.1.0/24"},
+		{prefix: "172.28.255.0/24", want: "172.29.0.0/24"},
+		{prefix: "10.199.0.0/16", want: "10.200.0.0/16"},
+		{prefix: "192.168.192.128/25", want: "192.168.193.0/25"},
+	}
+	for _, tt := range tests {
+		if got := nextPrefix(netip.MustParsePrefix(tt.prefix)); got.String() != tt.want {
+			t.Errorf("nextPrefix(%s) = %s, want %s", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestOverlapsAny(t *testing.T) {
+	tests := []struct {
+		prefix string
+		used   []netip.Prefix
+		want   bool
+	}{
+		{prefix: "172.28.0.0/24", used: nil, want: false},
+		{prefix: "172.28.0.0/24", used: prefixes("172.28.0.0/24"), want: true},
+		{prefix: "172.28.0.0/24", used: prefixes("172.16.0.0/12"), want: true},
+		{prefix: "172.28.0.0/24", used: prefixes("172.28.0.128/25"), want: true},
+		{prefix: "172.28.0.0/24", used: prefixes("172.28.1.0/24", "10.0.0.0/8"), want: false},
+	}
+	for _, tt := range tests {
+		if got := overlapsAny(netip.MustParsePrefix(tt.prefix), tt.used); got != tt.want {
+			t.Errorf("overlapsAny(%s, %v) = %t, want %t", tt.prefix, tt.used, got, tt.want)
+		}
+	}
+}
+
+func TestFirstFreeSubnet(t *testing.T) {
+	tests := []struct {
+		name    string
+		used    []netip.Prefix
+		want    string
+		wantErr bool
+	}{
+		{name: "nothing used", want: "172.28.0.0/24"},
+		{name: "collision with a docker network", used: prefixes("172.28.0.0/16"), want: "172.29.0.0/24"},
+		{name: "collision with a host address", used: prefixes("172.28.0.7/32", "172.28.1.0/24"), want: "172.28.2.0/24"},
+		{name: "vpn covers the first pool", used: prefixes("172.16.0.0/12"), want: "10.199.0.0/24"},
+		{name: "vpn covers the first pools", used: prefixes("172.16.0.0/12", "10.0.0.0/8"), want: "192.168.192.0/24"},
+		{name: "all pools used", used: prefixes("172.16.0.0/12", "10.0.0.0/8", "192.168.0.0/16"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := firstFreeSubnet(devSubnetPools, devSubnetBits, tt.used)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "--subnet") {
+					t.Fatalf("firstFreeSubnet() = %s, %v, want an error naming --subnet", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("firstFreeSubnet() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
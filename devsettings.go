@@ -0,0 +1,211 @@
+package grafanasdkclistarter
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/urfave/cli/v3"
+)
+
+// devImageFlags maps the services of the dev stack to the flag of their image
+var devImageFlags = map[string]CliValues{
+	"grafana":        CliDevGrafanaImage,
+	"prometheus":     CliDevPrometheusImage,
+	"loki":           CliDevLokiImage,
+	"tempo":          CliDevTempoImage,
+	"otel-collector": CliDevOtelCollectorImage,
+	"alertmanager":   CliDevAlertmanagerImage,
+}
+
+// devDefaultImages are pinned, so a new release does not change the dev stack silently
+var devDefaultImages = map[string]string{
+	"grafana":        "grafana/grafana:11.4.0",
+	"prometheus":     "prom/prometheus:v3.0.1",
+	"loki":           "grafana/loki:3.3.2",
+	"tempo":          "grafana/tempo:2.6.1",
+	"otel-collector": "otel/opentelemetry-collector-contrib:0.116.1",
+	"alertmanager":   "prom/alertmanager:v0.27.0",
+}
+
+func devImageFlagList(appName string) []cli.Flag {
+	var res []cli.Flag
+	for _, service := range sortedKeys(devImageFlags) {
+		res = append(res, &cli.StringFlag{
+			Name:    devImageFlags[service],
+			Sources: cli.EnvVars(GetFlagEnvByFlagName(devImageFlags[service], appName)),
+			Value:   devDefaultImages[service],
+			Usage:   fmt.Sprintf("image of %s", service),
+		})
+	}
+	return res
+}
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// devRunSettings are the validated images and grafana settings of dev run
+type devRunSettings struct {
+	images        map[string]string
+	grafanaEnv    map[string]string
+	adminUser     string
+	adminPassword string
+}
+
+// devRunSettingsOf validates the flags of dev run before any container starts
+func devRunSettingsOf(c *cli.Command) (devRunSettings, error) {
+	s := devRunSettings{
+		images:        map[string]string{},
+		grafanaEnv:    map[string]string{},
+		adminUser:     c.String(CliDevAdminUser),
+		adminPassword: c.String(CliDevAdminPassword),
+	}
+	for _, service := range sortedKeys(devImageFlags) {
+		image := c.String(devImageFlags[service])
+		if _, err := reference.ParseNormalizedNamed(image); err != nil {
+			return s, fmt.Errorf("--%s: %q is not a valid image: %w", devImageFlags[service], image, err)
+		}
+		s.images[service] = image
+	}
+	if s.adminUser == "" || s.adminPassword == "" {
+		return s, fmt.Errorf("--%s and --%s must not be empty", CliDevAdminUser, CliDevAdminPassword)
+	}
+	for _, e := range c.StringSlice(CliDevGrafanaEnv) {
+		k, v, ok := strings.Cut(e, "=")
+		if !ok || !envNamePattern.MatchString(k) {
+			return s, fmt.Errorf("--%s: %q is not a KEY=VALUE pair", CliDevGrafanaEnv, e)
+		}
+		s.grafanaEnv[k] = v
+	}
+	toggles, err := devListValue(CliDevFeatureToggles, c.StringSlice(CliDevFeatureToggles))
+	if err != nil {
+		return s, err
+	}
+	if toggles != "" {
+		s.grafanaEnv["GF_FEATURE_TOGGLES_ENABLE"] = toggles
+	}
+	plugins, err := devListValue(CliDevPlugins, c.StringSlice(CliDevPlugins))
+	if err != nil {
+		return s, err
+	}
+	if plugins != "" {
+		s.grafanaEnv["GF_INSTALL_PLUGINS"] = plugins
+	}
+	if c.Bool(CliDevAnonymous) {
+		s.grafanaEnv["GF_AUTH_ANONYMOUS_ENABLED"] = "true"
+		s.grafanaEnv["GF_AUTH_ANONYMOUS_ORG_ROLE"] = "Admin"
+	}
+	s.grafanaEnv["GF_SECURITY_ADMIN_USER"] = s.adminUser
+	s.grafanaEnv["GF_SECURITY_ADMIN_PASSWORD"] = s.adminPassword
+	return s, nil
+}
+
+// devListValue joins the values to the comma separated list grafana expects
+func devListValue(flag string, values []string) (string, error) {
+	entries := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" || strings.Contains(v, ",") {
+			return "", fmt.Errorf("--%s: %q is not a valid entry", flag, v)
+		}
+		entries = append(entries, v)
+	}
+	return strings.Join(entries, ","), nil
+}
+
+// setDevRunDefault changes the default of a dev run flag, values given by flag or env still win
+func setDevRunDefault(app *cli.Command, name string, value any) error {
+	for _, c := range app.Commands {
+		if c.Name != "dev" {
+			continue
+		}
+		for _, sc := range c.Commands {
+			if sc.Name != "run" {
+				continue
+			}
+			for _, f := range sc.Flags {
+				if !slices.Contains(f.Names(), name) {
+					continue
+				}
+				switch flag := f.(type) {
+				case *cli.StringFlag:
+					v, ok := value.(string)
+					if !ok {
+						return fmt.Errorf("flag %s needs a string", name)
+					}
+					flag.Value = v
+				case *cli.BoolFlag:
+					v, ok := value.(bool)
+					if !ok {
+						return fmt.Errorf("flag %s needs a bool", name)
+					}
+					flag.Value = v
+				case *cli.StringSliceFlag:
+					v, ok := value.([]string)
+					if !ok {
+						return fmt.Errorf("flag %s needs a string slice", name)
+					}
+					flag.Value = append(flag.Value, v...)
+				default:
+					return fmt.Errorf("flag %s can not be set by an option", name)
+				}
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("dev run has no flag %s", name)
+}
+
+// DevImage sets the image of a service of the dev stack (grafana, prometheus, loki, tempo, otel-collector, alertmanager)
+func DevImage(service, image string) Option {
+	return func(runner *Runner, app *cli.Command) error {
+		flag, ok := devImageFlags[service]
+		if !ok {
+			return fmt.Errorf("unknown dev service %s", service)
+		}
+		return setDevRunDefault(app, flag, image)
+	}
+}
+
+// DevGrafanaEnv adds env vars to the dev grafana, for example GF_LOG_LEVEL
+func DevGrafanaEnv(env map[string]string) Option {
+	return func(runner *Runner, app *cli.Command) error {
+		var values []string
+		for _, k := range sortedKeys(env) {
+			values = append(values, k+"="+env[k])
+		}
+		return setDevRunDefault(app, CliDevGrafanaEnv, values)
+	}
+}
+
+// DevGrafanaFeatureToggles enables feature toggles of the dev grafana
+func DevGrafanaFeatureToggles(toggles ...string) Option {
+	return func(runner *Runner, app *cli.Command) error {
+		return setDevRunDefault(app, CliDevFeatureToggles, toggles)
+	}
+}
+
+// DevGrafanaPlugins installs plugins into the dev grafana (GF_INSTALL_PLUGINS syntax)
+func DevGrafanaPlugins(plugins ...string) Option {
+	return func(runner *Runner, app *cli.Command) error {
+		return setDevRunDefault(app, CliDevPlugins, plugins)
+	}
+}
+
+// DevGrafanaAnonymous allows the dev grafana without login
+func DevGrafanaAnonymous() Option {
+	return func(runner *Runner, app *cli.Command) error {
+		return setDevRunDefault(app, CliDevAnonymous, true)
+	}
+}
+
+// DevGrafanaAdmin sets the admin credentials of the dev grafana
+func DevGrafanaAdmin(user, password string) Option {
+	return func(runner *Runner, app *cli.Command) error {
+		if err := setDevRunDefault(app, CliDevAdminUser, user); err != nil {
+			return err
+		}
+		return setDevRunDefault(app, CliDevAdminPassword, password)
+	}
+}
@@ -0,0 +1,83 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+)
+
+func TestDevListValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    string
+		wantErr bool
+	}{
+		{name: "none", values: nil, want: ""},
+		{name: "one", values: []string{"publicDashboards"}, want: "publicDashboards"},
+		{name: "trimmed", values: []string{" a", " b ", "c\t"}, want: "a,b,c"},
+		{name: "empty entry", values: []string{"a", " "}, wantErr: true},
+		{name: "comma inside entry", values: []string{"a,b"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := devListValue(CliDevFeatureToggles, tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("devListValue() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("devListValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDevRunSettingsOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantEnv map[string]string
+		wantErr string
+	}{
+		{
+			name:    "lists without whitespace",
+			args:    []string{"--feature-toggles", " a, b", "--plugins", "grafana-clock-panel 1.0.1 , grafana-piechart-panel"},
+			wantEnv: map[string]string{"GF_FEATURE_TOGGLES_ENABLE": "a,b", "GF_INSTALL_PLUGINS": "grafana-clock-panel 1.0.1,grafana-piechart-panel"},
+		},
+		{name: "empty toggle", args: []string{"--feature-toggles", "a,,b"}, wantErr: "--feature-toggles"},
+		{name: "grafana env", args: []string{"--grafana-env", "GF_LOG_LEVEL=debug"}, wantEnv: map[string]string{"GF_LOG_LEVEL": "debug"}},
+		{name: "invalid grafana env", args: []string{"--grafana-env", "1=debug"}, wantErr: "KEY=VALUE"},
+		{name: "invalid image", args: []string{"--grafana-image", "Grafana:latest"}, wantErr: "not a valid image"},
+		{name: "empty admin", args: []string{"--admin-user", ""}, wantErr: "must not be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, err := NewCli("test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var settings devRunSettings
+			subCommand(t, app, "dev", "run").Action = func(ctx context.Context, c *cli.Command) error {
+				settings, err = devRunSettingsOf(c)
+				return err
+			}
+			err = app.Run(context.Background(), append([]string{"test", "dev", "run", "--datasource", "prometheus"}, tt.args...))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("devRunSettingsOf() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.wantEnv {
+				if got := settings.grafanaEnv[k]; got != v {
+					t.Errorf("grafana env %s = %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/urfave/cli/v3"
+)
+
+const (
+	// DevLabelStack is set at all containers, networks and volumes of the dev stack, the value is the app name
+	DevLabelStack = "grafana-sdk-cli-starter.stack"
+	// DevLabelService names the service of a dev container
+	DevLabelService = "grafana-sdk-cli-starter.service"
+	// devStateFile keeps the endpoints of a detached dev stack
+	devStateFile = ".dev-state.json"
+)
+
+// devStack identifies the containers of one dev run
+type devStack struct {
+	app     string
+	network string
+}
+
+func (s devStack) labels(service string) map[string]string {
+	return map[string]string{DevLabelStack: s.app, DevLabelService: service}
+}
+
+// volume returns a named volume of the persist mode, it survives the dev stack until dev reset
+func (s devStack) volume(service, target string) mount.Mount {
+	return mount.Mount{
+		Type:          mount.TypeVolume,
+		Source:        fmt.Sprintf("%s-dev-%s", s.app, service),
+		Target:        target,
+		VolumeOptions: &mount.VolumeOptions{Labels: s.labels(service)},
+	}
+}
+
+// DevState is written by dev run --detach and read by dev env and dev status
+type DevState struct {
+	App              string    `json:"app"`
+	Network          string    `json:"network"`
+	StartedAt        time.Time `json:"startedAt"`
+	GrafanaUrl       string    `json:"grafanaUrl"`
+	ApiKey           string    `json:"apiKey"`
+	PrometheusUrl    string    `json:"prometheusUrl"`
+	Datasource       string    `json:"datasource"`
+	LokiPushUrl      string    `json:"lokiPushUrl,omitempty"`
+	OtlpGrpcEndpoint string    `json:"otlpGrpcEndpoint,omitempty"`
+	OtlpHttpEndpoint string    `json:"otlpHttpEndpoint,omitempty"`
+	AlertmanagerUrl  string    `json:"alertmanagerUrl,omitempty"`
+}
+
+func writeDevState(state DevState) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	// the state contains the api key, WriteFile keeps the mode of a file written by an older version
+	if err := os.WriteFile(devStateFile, b, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(devStateFile, 0600)
+}
+
+func readDevState() (DevState, error) {
+	var state DevState
+	b, err := os.ReadFile(devStateFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, fmt.Errorf("no detached dev stack found, start one with dev run --%s", CliDevDetach)
+		}
+		return state, err
+	}
+	err = json.Unmarshal(b, &state)
+	return state, err
+}
+
+func (r *Runner) stackFilter() filters.Args {
+	return filters.NewArgs(filters.Arg("label", DevLabelStack+"="+r.appName))
+}
+
+// prepareDetach checks that dev run can keep its stack running in background. The reaper of testcontainers
+// removes all containers, the network and the volumes once this process ends. testcontainers reads its config
+// only once, so the reaper is disabled before the first testcontainers call.
+func (r *Runner) prepareDetach(ctx context.Context, c *cli.Command) error {
+	os.Setenv("TESTCONTAINERS_RYUK_DISABLED", "true")
+	if c.Bool(CliDevWatch) {
+		return fmt.Errorf("--%s can not be used with --%s", CliDevWatch, CliDevDetach)
+	}
+	if c.Bool(CliDevAlertmanager) {
+		return fmt.Errorf("--%s can not be used with --%s, the console webhook of alertmanager ends with this process", CliDevAlertmanager, CliDevDetach)
+	}
+	if len(r.devSynthetic) > 0 || c.String(CliDevSyntheticFile) != "" {
+		return fmt.Errorf("synthetic metrics (--%s or DevSyntheticMetrics) can not be used with --%s, the exporter ends with this process", CliDevSyntheticFile, CliDevDetach)
+	}
+	running, err := r.devContainers(ctx)
+	if err != nil {
+		return err
+	}
+	if len(running) > 0 {
+		return fmt.Errorf("dev stack is running already, use dev stop first")
+	}
+	return nil
+}
+
+func (r *Runner) devContainers(ctx context.Context) ([]string, error) {
+	docker, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to docker: %w", err)
+	}
+	defer docker.Close()
+	list, err := docker.ContainerList(ctx, container.ListOptions{All: true, Filters: r.stackFilter()})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list dev containers: %w", err)
+	}
+	res := make([]string, 0, len(list))
+	for _, c := range list {
+		res = append(res, c.ID)
+	}
+	return res, nil
+}
+
+func (r *Runner) DevStatus(ctx context.Context, c *cli.Command) error {
+	docker, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to connect to docker: %w", err)
+	}
+	defer docker.Close()
+	list, err := docker.ContainerList(ctx, container.ListOptions{All: true, Filters: r.stackFilter()})
+	if err != nil {
+		return fmt.Errorf("unable to list dev containers: %w", err)
+	}
+	if len(list) == 0 {
+		fmt.Println("No dev stack running")
+		return nil
+	}
+	for _, ct := range list {
+		var ports []string
+		for _, p := range ct.Ports {
+			if p.PublicPort != 0 {
+				ports = append(ports, fmt.Sprintf("%d->%d", p.PublicPort, p.PrivatePort))
+			}
+		}
+		fmt.Printf("%-16s %-10s %-24s %s\n", ct.Labels[DevLabelService], ct.State, ct.Status, strings.Join(ports, ", "))
+	}
+	if state, err := readDevState(); err == nil {
+		fmt.Printf("Grafana endpoint: %s \n", state.GrafanaUrl)
+		fmt.Printf("Prometheus endpoint: %s \n", state.PrometheusUrl)
+		fmt.Printf("Started at: %s \n", state.StartedAt.Local().Format(time.DateTime))
+	}
+	return nil
+}
+
+// DevEnv prints the exports to use the detached dev stack, for example with eval "$(go run . dev env)"
+func (r *Runner) DevEnv(ctx context.Context, c *cli.Command) error {
+	state, err := readDevState()
+	if err != nil {
+		return err
+	}
+	exports := [][2]string{
+		{GetFlagEnvByFlagName(CliServer, r.appName), state.GrafanaUrl},
+		{GetFlagEnvByFlagName(CliApiKey, r.appName), state.ApiKey},
+		{GetFlagEnvByFlagName(CliDevDatasourceName, r.appName), state.Datasource},
+	}
+	if state.OtlpHttpEndpoint != "" {
+		exports = append(exports, [2]string{"OTEL_EXPORTER_OTLP_ENDPOINT", state.OtlpHttpEndpoint})
+	}
+	for _, e := range exports {
+		fmt.Printf("export %s=%q\n", e[0], e[1])
+	}
+	return nil
+}
+
+// DevStop removes all containers and networks of the dev stack. Persisted volumes are kept.
+func (r *Runner) DevStop(ctx context.Context, c *cli.Command) error {
+	docker, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to connect to docker: %w", err)
+	}
+	defer docker.Close()
+	ids, err := r.devContainers(ctx)
+	if err != nil {
+		return err
+	}
+	errList := errors.Join(nil)
+	for _, id := range ids {
+		if err := docker.ContainerRemove(ctx, id, container.RemoveOptions{Force: true, RemoveVolumes: true}); err != nil {
+			errList = errors.Join(errList, fmt.Errorf("unable to remove dev container %s: %w", id, err))
+		}
+	}
+	networks, err := docker.NetworkList(ctx, network.ListOptions{Filters: r.stackFilter()})
+	if err != nil {
+		return errors.Join(errList, fmt.Errorf("unable to list dev networks: %w", err))
+	}
+	for _, n := range networks {
+		if err := docker.NetworkRemove(ctx, n.ID); err != nil {
+			errList = errors.Join(errList, fmt.Errorf("unable to remove dev network %s: %w", n.Name, err))
+		}
+	}
+	if errList != nil {
+		return errList
+	}
+	if err := os.Remove(devStateFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	fmt.Printf("Stopped %d container(s)\n", len(ids))
+	return nil
+}
+
+// DevReset deletes the persisted prometheus and grafana data
+func (r *Runner) DevReset(ctx context.Context, c *cli.Command) error {
+	ids, err := r.devContainers(ctx)
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		return fmt.Errorf("dev stack is running, stop it first (dev stop)")
+	}
+	docker, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to connect to docker: %w", err)
+	}
+	defer docker.Close()
+	volumes, err := docker.VolumeList(ctx, volume.ListOptions{Filters: r.stackFilter()})
+	if err != nil {
+		return fmt.Errorf("unable to list dev volumes: %w", err)
+	}
+	errList := errors.Join(nil)
+	for _, v := range volumes.Volumes {
+		if err := docker.VolumeRemove(ctx, v.Name, false); err != nil {
+			errList = errors.Join(errList, fmt.Errorf("unable to remove dev volume %s: %w", v.Name, err))
+			continue
+		}
+		fmt.Printf("volume %s: deleted\n", v.Name)
+	}
+	return errList
+}
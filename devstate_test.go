@@ -0,0 +1,166 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/urfave/cli/v3"
+)
+
+// detachTestChild selects the part of a detach test that runs in a child process, testcontainers reads its config only once per process
+const detachTestChild = "DETACH_TEST_CHILD"
+
+// runDetachChild runs the given test in a new process of the test binary
+func runDetachChild(t *testing.T, test, mode string, env ...string) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^"+test+"$", "-test.v")
+	cmd.Env = append(os.Environ(), append([]string{detachTestChild + "=" + mode, "TESTCONTAINERS_RYUK_DISABLED="}, env...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("child process failed: %v\n%s", err, out)
+	}
+}
+
+func TestPrepareDetachDisablesReaper(t *testing.T) {
+	if os.Getenv(detachTestChild) == "reaper" {
+		r := &Runner{appName: "detach-test"}
+		// --watch is rejected before any docker call, the reaper has to be disabled anyway
+		cmd := &cli.Command{Name: "run", Flags: []cli.Flag{&cli.BoolFlag{Name: CliDevWatch}}, Action: func(ctx context.Context, c *cli.Command) error {
+			return r.prepareDetach(ctx, c)
+		}}
+		if err := cmd.Run(context.Background(), []string{"run", "--" + CliDevWatch}); err == nil {
+			t.Fatal("--watch is accepted together with --detach")
+		}
+		if !testcontainers.ReadConfig().RyukDisabled {
+			fmt.Println("reaper is enabled after prepareDetach")
+			os.Exit(1)
+		}
+		return
+	}
+	runDetachChild(t, "TestPrepareDetachDisablesReaper", "reaper")
+}
+
+func TestPrepareDetachRejectsProcessBoundServices(t *testing.T) {
+	// prepareDetach disables the reaper of this process, t.Setenv restores it
+	t.Setenv("TESTCONTAINERS_RYUK_DISABLED", os.Getenv("TESTCONTAINERS_RYUK_DISABLED"))
+	tests := []struct {
+		name    string
+		args    []string
+		runner  *Runner
+		wantErr string
+	}{
+		{name: "watch", args: []string{"--" + CliDevWatch}, runner: &Runner{}, wantErr: CliDevWatch},
+		{name: "alertmanager", args: []string{"--" + CliDevAlertmanager}, runner: &Runner{}, wantErr: CliDevAlertmanager},
+		{name: "synthetic file", args: []string{"--" + CliDevSyntheticFile, "metrics.yaml"}, runner: &Runner{}, wantErr: "synthetic metrics"},
+		{name: "synthetic option", runner: &Runner{devSynthetic: []SyntheticMetric{{Name: "up", Shape: ShapeCounter}}}, wantErr: "synthetic metrics"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cli.Command{
+				Name: "run",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: CliDevWatch},
+					&cli.BoolFlag{Name: CliDevAlertmanager},
+					&cli.StringFlag{Name: CliDevSyntheticFile},
+				},
+				Action: tt.runner.prepareDetach,
+			}
+			err := cmd.Run(context.Background(), append([]string{"run"}, tt.args...))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("prepareDetach() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDetachedContainerSurvivesProcess(t *testing.T) {
+	const app = "detach-survive-test"
+	if os.Getenv(detachTestChild) == "container" {
+		r := &Runner{appName: app}
+		ctx := context.Background()
+		if err := r.prepareDetach(ctx, &cli.Command{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+			ContainerRequest: testcontainers.ContainerRequest{
+				Image:  "alpine:3",
+				Cmd:    []string{"sleep", "300"},
+				Labels: devStack{app: app}.labels("sleep"),
+			},
+			Started: true,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if testing.Short() {
+		t.Skip("starts a docker container")
+	}
+	// testcontainers panics without docker
+	if err := exec.Command("docker", "info").Run(); err != nil {
+		t.Skipf("docker is not available: %s", err)
+	}
+	ctx := context.Background()
+	docker, err := testcontainers.NewDockerClientWithOpts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Runner{appName: app}
+	t.Cleanup(func() {
+		list, _ := docker.ContainerList(ctx, container.ListOptions{All: true, Filters: r.stackFilter()})
+		for _, ct := range list {
+			docker.ContainerRemove(ctx, ct.ID, container.RemoveOptions{Force: true})
+		}
+	})
+
+	runDetachChild(t, "TestDetachedContainerSurvivesProcess", "container")
+	// the reaper removes everything some seconds after the connection of its creator is lost
+	time.Sleep(20 * time.Second)
+
+	list, err := docker.ContainerList(ctx, container.ListOptions{Filters: r.stackFilter()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 {
+		t.Errorf("got %d running containers of the detached stack after its process ended, want 1", len(list))
+	}
+}
+
+func TestWriteDevStateIsPrivate(t *testing.T) {
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(pwd) })
+
+	// a state of an older version readable by everyone
+	if err := os.WriteFile(devStateFile, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeDevState(DevState{App: "test", ApiKey: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(devStateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("mode of %s = %o, want 600", devStateFile, mode)
+	}
+	state, err := readDevState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.ApiKey != "secret" {
+		t.Errorf("api key = %q, want secret", state.ApiKey)
+	}
+}
@@ -0,0 +1,108 @@
+package grafanasdkclistarter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// volatileDashboardFields are set by grafana on save and never part of the code
+var volatileDashboardFields = []string{"id", "version", "iteration"}
+
+type FieldDiff struct {
+	Path   string
+	Before any
+	After  any
+}
+
+func (f FieldDiff) String() string {
+	switch {
+	case f.Before == nil:
+		return fmt.Sprintf("+ %s: %s", f.Path, formatDiffValue(f.After))
+	case f.After == nil:
+		return fmt.Sprintf("- %s: %s", f.Path, formatDiffValue(f.Before))
+	default:
+		return fmt.Sprintf("~ %s: %s => %s", f.Path, formatDiffValue(f.Before), formatDiffValue(f.After))
+	}
+}
+
+// normalizeDashboard converts a dashboard into its generic json representation
+// and removes all fields grafana manages on its own
+func normalizeDashboard(d any) (map[string]any, error) {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal dashboard: %w", err)
+	}
+	var res map[string]any
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal dashboard: %w", err)
+	}
+	for _, f := range volatileDashboardFields {
+		delete(res, f)
+	}
+	return res, nil
+}
+
+// diffValues compares two generic json values and returns every changed leaf
+func diffValues(path string, before, after any) []FieldDiff {
+	switch b := before.(type) {
+	case map[string]any:
+		a, ok := after.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(a)+len(b))
+		for k := range b {
+			keys = append(keys, k)
+		}
+		for k := range a {
+			if _, ok := b[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		var res []FieldDiff
+		for _, k := range keys {
+			res = append(res, diffValues(joinDiffPath(path, k), b[k], a[k])...)
+		}
+		return res
+	case []any:
+		a, ok := after.([]any)
+		if !ok {
+			break
+		}
+		var res []FieldDiff
+		for i := 0; i < max(len(a), len(b)); i++ {
+			var bv, av any
+			if i < len(b) {
+				bv = b[i]
+			}
+			if i < len(a) {
+				av = a[i]
+			}
+			res = append(res, diffValues(fmt.Sprintf("%s[%d]", path, i), bv, av)...)
+		}
+		return res
+	}
+	if reflect.DeepEqual(before, after) {
+		return nil
+	}
+	return []FieldDiff{{Path: path, Before: before, After: after}}
+}
+
+func joinDiffPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func formatDiffValue(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,81 @@
+package grafanasdkclistarter
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDiffValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   []string
+	}{
+		{
+			name:   "equal",
+			before: `{"title":"a","panels":[{"id":1}]}`,
+			after:  `{"title":"a","panels":[{"id":1}]}`,
+		},
+		{
+			name:   "changed leaf",
+			before: `{"title":"a"}`,
+			after:  `{"title":"b"}`,
+			want:   []string{`~ title: "a" => "b"`},
+		},
+		{
+			name:   "added and removed keys are sorted",
+			before: `{"b":1,"c":true}`,
+			after:  `{"a":"x","b":1}`,
+			want:   []string{`+ a: "x"`, `- c: true`},
+		},
+		{
+			name:   "nested paths",
+			before: `{"panels":[{"targets":[{"expr":"up"}]}]}`,
+			after:  `{"panels":[{"targets":[{"expr":"down"}]}]}`,
+			want:   []string{`~ panels[0].targets[0].expr: "up" => "down"`},
+		},
+		{
+			name:   "longer list",
+			before: `{"tags":["a"]}`,
+			after:  `{"tags":["a","b"]}`,
+			want:   []string{`+ tags[1]: "b"`},
+		},
+		{
+			name:   "type change",
+			before: `{"gridPos":{"h":8}}`,
+			after:  `{"gridPos":[8]}`,
+			want:   []string{`~ gridPos: {"h":8} => [8]`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before, after any
+			if err := json.Unmarshal([]byte(tt.before), &before); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.after), &after); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, d := range diffValues("", before, after) {
+				got = append(got, d.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffValues() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeDashboard(t *testing.T) {
+	got, err := normalizeDashboard(map[string]any{"id": 3, "version": 7, "iteration": 1, "uid": "x", "title": "t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"uid": "x", "title": "t"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeDashboard() = %v, want %v", got, want)
+	}
+}
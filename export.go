@@ -0,0 +1,956 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/fasibio/grafanaSdkCliStarter/override"
+	"github.com/fasibio/grafanaSdkCliStarter/query"
+	"github.com/fasibio/grafanaSdkCliStarter/variable"
+	"github.com/grafana/grafana-foundation-sdk/go/bargauge"
+	"github.com/grafana/grafana-foundation-sdk/go/cog"
+	"github.com/grafana/grafana-foundation-sdk/go/cog/variants"
+	"github.com/grafana/grafana-foundation-sdk/go/dashboard"
+	"github.com/grafana/grafana-foundation-sdk/go/gauge"
+	"github.com/grafana/grafana-foundation-sdk/go/piechart"
+	"github.com/grafana/grafana-foundation-sdk/go/prometheus"
+	"github.com/grafana/grafana-foundation-sdk/go/stat"
+	"github.com/grafana/grafana-foundation-sdk/go/table"
+	"github.com/grafana/grafana-foundation-sdk/go/text"
+	"github.com/grafana/grafana-foundation-sdk/go/timeseries"
+	"github.com/grafana/grafana-openapi-client-go/client/search"
+	"github.com/urfave/cli/v3"
+)
+
+const sdkImportPrefix = "github.com/grafana/grafana-foundation-sdk/go/"
+
+const repoImportPrefix = "github.com/fasibio/grafanaSdkCliStarter/"
+
+// exportUnits maps the grafana unit ids to the constants of the unit package
+var exportUnits = map[string]string{
+	"reqps":       "RequestPerSeconds",
+	"ms":          "Milliseconds",
+	"percentunit": "PercentUnit",
+	"s":           "Seconds",
+	"bytes":       "Bytes",
+	"bps":         "BytesPerSecond",
+	"kbps":        "KilobitsPerSecond",
+	"Mbps":        "MegabitsPerSecond",
+	"Gbps":        "GigabitsPerSecond",
+	"short":       "Short",
+	"kB":          "Kilobytes",
+	"MB":          "Megabytes",
+	"GB":          "Gigabytes",
+	"TB":          "Terabytes",
+	"bits":        "Bits",
+	"Hz":          "Hertz",
+	"kHz":         "Kilohertz",
+	"MHz":         "Megahertz",
+	"GHz":         "Gigahertz",
+	"°C":          "Celsius",
+	"°F":          "Fahrenheit",
+	"K":           "Kelvin",
+	"V":           "Volts",
+	"mV":          "Millivolts",
+	"kV":          "Kilovolts",
+	"A":           "Amperes",
+	"mA":          "Milliamperes",
+	"W":           "Watts",
+	"kW":          "Kilowatts",
+	"MW":          "Megawatts",
+	"J":           "Joules",
+	"kJ":          "Kilojoules",
+	"Wh":          "WattHours",
+	"kWh":         "KilowattHours",
+	"MWh":         "MegawattHours",
+	"Pa":          "Pascals",
+	"hPa":         "Hectopascals",
+	"kPa":         "Kilopascals",
+	"bar":         "Bar",
+	"m":           "Meters",
+	"km":          "Kilometers",
+	"mi":          "Miles",
+	"ft":          "Feet",
+	"in":          "Inches",
+	"L":           "Liters",
+	"mL":          "Milliliters",
+	"m³":          "CubicMeters",
+	"gal":         "Gallons",
+	"g":           "Grams",
+	"kg":          "Kilograms",
+	"t":           "MetricTons",
+	"lb":          "Pounds",
+	"oz":          "Ounces",
+	"m/s":         "MetersPerSecond",
+	"km/h":        "KilometersPerHour",
+	"mph":         "MilesPerHour",
+	"°":           "Degrees",
+	"rad":         "Radians",
+	"$":           "USD",
+	"€":           "EUR",
+	"£":           "GBP",
+}
+
+func (r *Runner) Export(ctx context.Context, c *cli.Command) error {
+	uids, err := r.exportUIDs(ctx, c)
+	if err != nil {
+		return fmt.Errorf("failed export: %w", err)
+	}
+	if len(uids) == 0 {
+		return fmt.Errorf("failed export: no dashboard found, use --%s, --%s or --%s", CliExportUid, CliExportFolder, CliExportTag)
+	}
+	output := c.String(CliExportOutput)
+	pkg := c.String(CliExportPackage)
+	if err := os.MkdirAll(output, os.ModePerm); err != nil {
+		return err
+	}
+
+	var funcs []string
+	for _, uid := range uids {
+		d, _, found, err := r.getRemoteDashboard(uid)
+		if err != nil {
+			return fmt.Errorf("failed export: %w", err)
+		}
+		if !found {
+			return fmt.Errorf("failed export: dashboard %s not found", uid)
+		}
+		// the marker of this cli is added again on apply
+		delete(d, OwnerMetadataKey)
+		title, _ := d["title"].(string)
+		funcName := exportFuncName(title, funcs)
+		funcs = append(funcs, funcName)
+
+		src, err := exportDashboard(pkg, funcName, d)
+		if err != nil {
+			return fmt.Errorf("failed export %s: %w", uid, err)
+		}
+		file := filepath.Join(output, exportFileName(funcName))
+		if err := os.WriteFile(file, src, 0644); err != nil {
+			return err
+		}
+		fmt.Printf("%s: %s\n", title, file)
+	}
+
+	src, err := exportDashboards(pkg, funcs)
+	if err != nil {
+		return fmt.Errorf("failed export: %w", err)
+	}
+	file := filepath.Join(output, "dashboards.go")
+	if err := os.WriteFile(file, src, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Use %s.Dashboards with DashboardBuilder (%s)\n", pkg, file)
+	return nil
+}
+
+// exportUIDs returns the uids given by flag and all dashboards of the given folder or tags
+func (r *Runner) exportUIDs(ctx context.Context, c *cli.Command) ([]string, error) {
+	uids := slices.Clone(c.StringSlice(CliExportUid))
+	folder := c.String(CliExportFolder)
+	tags := c.StringSlice(CliExportTag)
+	if folder == "" && len(tags) == 0 {
+		return uids, nil
+	}
+	for page := int64(1); ; page++ {
+		params := search.NewSearchParamsWithContext(ctx).
+			WithType(cog.ToPtr("dash-db")).
+			WithLimit(cog.ToPtr(searchPageSize)).
+			WithPage(cog.ToPtr(page))
+		if folder != "" {
+			params = params.WithFolderUIDs([]string{folder})
+		}
+		if len(tags) > 0 {
+			params = params.WithTag(tags)
+		}
+		hits, err := r.client.Search.Search(params)
+		if err != nil {
+			return nil, fmt.Errorf("unable to search dashboards: %w", err)
+		}
+		for _, h := range hits.Payload {
+			if !slices.Contains(uids, h.UID) {
+				uids = append(uids, h.UID)
+			}
+		}
+		if int64(len(hits.Payload)) < searchPageSize {
+			return uids, nil
+		}
+	}
+}
+
+// exportFuncName converts a dashboard title into an unique exported go identifier
+func exportFuncName(title string, used []string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range title {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) || r > unicode.MaxASCII {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	name := sb.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "D" + name
+	}
+	name += "Dashboard"
+	res := name
+	for i := 2; slices.Contains(used, res); i++ {
+		res = fmt.Sprintf("%s%d", name, i)
+	}
+	return res
+}
+
+func exportFileName(funcName string) string {
+	var sb strings.Builder
+	for i, r := range funcName {
+		if unicode.IsUpper(r) && i > 0 {
+			sb.WriteRune('_')
+		}
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	return sb.String() + ".go"
+}
+
+// exportDashboards generates the DashboardCreator calling all exported dashboards and the raw json helpers
+func exportDashboards(pkg string, funcs []string) ([]byte, error) {
+	var sb strings.Builder
+	sb.WriteString("// Code generated by dashboard export.\n\n")
+	fmt.Fprintf(&sb, "package %s\n\n", pkg)
+	sb.WriteString("import (\n\t\"encoding/json\"\n\n")
+	fmt.Fprintf(&sb, "\t%q\n\t%q\n)\n\n", sdkImportPrefix+"dashboard", "github.com/urfave/cli/v3")
+	sb.WriteString("// Dashboards returns all exported dashboards. Use it with the DashboardBuilder option.\n")
+	sb.WriteString("func Dashboards(folderName string, c *cli.Command) ([]dashboard.Dashboard, error) {\n")
+	sb.WriteString("\tvar res []dashboard.Dashboard\n")
+	sb.WriteString("\tfor _, f := range []func(string, *cli.Command) (dashboard.Dashboard, error){\n")
+	for _, f := range funcs {
+		fmt.Fprintf(&sb, "\t\t%s,\n", f)
+	}
+	sb.WriteString("\t} {\n\t\td, err := f(folderName, c)\n\t\tif err != nil {\n\t\t\treturn nil, err\n\t\t}\n\t\tres = append(res, d)\n\t}\n\treturn res, nil\n}\n\n")
+	sb.WriteString("// rawPanel is a panel without a matching builder\n")
+	sb.WriteString("type rawPanel string\n\n")
+	sb.WriteString("func (p rawPanel) Build() (dashboard.Panel, error) {\n\tvar res dashboard.Panel\n\terr := json.Unmarshal([]byte(p), &res)\n\treturn res, err\n}\n\n")
+	sb.WriteString("// rawRow is a row without a matching builder\n")
+	sb.WriteString("type rawRow string\n\n")
+	sb.WriteString("func (r rawRow) Build() (dashboard.RowPanel, error) {\n\tvar res dashboard.RowPanel\n\terr := json.Unmarshal([]byte(r), &res)\n\treturn res, err\n}\n\n")
+	sb.WriteString("// rawVariable is a variable without a matching builder\n")
+	sb.WriteString("type rawVariable string\n\n")
+	sb.WriteString("func (v rawVariable) Build() (dashboard.VariableModel, error) {\n\tvar res dashboard.VariableModel\n\terr := json.Unmarshal([]byte(v), &res)\n\treturn res, err\n}\n\n")
+	sb.WriteString("// rawFields sets the dashboard fields without a builder method, null removes a builder default\n")
+	sb.WriteString("func rawFields(d dashboard.Dashboard, fields string) (dashboard.Dashboard, error) {\n\terr := json.Unmarshal([]byte(fields), &d)\n\treturn d, err\n}\n")
+	return format.Source([]byte(sb.String()))
+}
+
+// dashboardExport collects the generated builder chain and the needed imports of one dashboard
+type dashboardExport struct {
+	sb      strings.Builder
+	imports map[string]bool
+}
+
+func (e *dashboardExport) use(importPath string) {
+	e.imports[importPath] = true
+}
+
+func (e *dashboardExport) line(format string, args ...any) {
+	fmt.Fprintf(&e.sb, "\t\t"+format+"\n", args...)
+}
+
+// attempt runs the builder export of one element with its own imports, they are only kept if f succeeds
+func (e *dashboardExport) attempt(f func(e *dashboardExport) bool) bool {
+	sub := &dashboardExport{imports: map[string]bool{}}
+	if !f(sub) {
+		return false
+	}
+	maps.Copy(e.imports, sub.imports)
+	return true
+}
+
+// rawBuilder builds a resource from its json like the raw types of the generated code
+type rawBuilder[T any] string
+
+func (r rawBuilder[T]) Build() (T, error) {
+	var res T
+	err := json.Unmarshal([]byte(r), &res)
+	return res, err
+}
+
+// sameBuild is true if both builders produce the same json. Every builder export is compared with
+// its raw json fallback, so applying the exported code never changes the dashboard.
+func sameBuild[T any](a, b cog.Builder[T]) bool {
+	resA, err := a.Build()
+	if err != nil {
+		return false
+	}
+	resB, err := b.Build()
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(jsonValue(resA), jsonValue(resB))
+}
+
+// jsonValue converts a value into its generic json representation
+func jsonValue(v any) any {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var res any
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil
+	}
+	return res
+}
+
+func exportDashboard(pkg, funcName string, d map[string]any) ([]byte, error) {
+	e := &dashboardExport{imports: map[string]bool{}}
+	e.use(sdkImportPrefix + "dashboard")
+	e.use("github.com/urfave/cli/v3")
+
+	title, _ := d["title"].(string)
+	uid, _ := d["uid"].(string)
+	b := dashboard.NewDashboardBuilder(title)
+	e.line("Uid(%q).", uid)
+	b.Uid(uid)
+	if v, ok := d["description"].(string); ok {
+		e.line("Description(%q).", v)
+		b.Description(v)
+	}
+	if tags := stringList(d["tags"]); len(tags) > 0 {
+		e.line("Tags(%s).", goLiteral(tags))
+		b.Tags(tags)
+	}
+	if v, ok := d["timezone"].(string); ok {
+		e.line("Timezone(%q).", v)
+		b.Timezone(v)
+	}
+	if v, ok := d["refresh"].(string); ok {
+		e.line("Refresh(%q).", v)
+		b.Refresh(v)
+	}
+	if t, ok := d["time"].(map[string]any); ok {
+		from, _ := t["from"].(string)
+		to, _ := t["to"].(string)
+		e.line("Time(%q, %q).", from, to)
+		b.Time(from, to)
+	}
+	if editable, ok := d["editable"].(bool); ok {
+		if editable {
+			e.line("Editable().")
+			b.Editable()
+		} else {
+			e.line("Readonly().")
+			b.Readonly()
+		}
+	}
+	fields, err := exportRawFields(b, d)
+	if err != nil {
+		return nil, err
+	}
+	if templating, ok := d["templating"].(map[string]any); ok {
+		list, _ := templating["list"].([]any)
+		for _, v := range list {
+			if m, ok := v.(map[string]any); ok {
+				e.exportVariable(m)
+			}
+		}
+	}
+	panels, _ := d["panels"].([]any)
+	for _, p := range panels {
+		m, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+		if m["type"] == "row" {
+			e.exportRow(m)
+			continue
+		}
+		code, _ := e.panelBuilder(m)
+		e.line("WithPanel(%s).", code)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "// Code generated by dashboard export from grafana dashboard %q (%s).\n\n", title, uid)
+	fmt.Fprintf(&sb, "package %s\n\nimport (\n", pkg)
+	imports := sortedKeys(e.imports)
+	for _, i := range imports {
+		fmt.Fprintf(&sb, "\t%q\n", i)
+	}
+	sb.WriteString(")\n\n")
+	fmt.Fprintf(&sb, "func %s(folderName string, c *cli.Command) (dashboard.Dashboard, error) {\n", funcName)
+	if len(fields) == 0 {
+		fmt.Fprintf(&sb, "\treturn dashboard.NewDashboardBuilder(%q).\n", title)
+		sb.WriteString(e.sb.String())
+		sb.WriteString("\t\tBuild()\n}\n")
+	} else {
+		fmt.Fprintf(&sb, "\td, err := dashboard.NewDashboardBuilder(%q).\n", title)
+		sb.WriteString(e.sb.String())
+		sb.WriteString("\t\tBuild()\n\tif err != nil {\n\t\treturn d, err\n\t}\n")
+		fmt.Fprintf(&sb, "\treturn rawFields(d, %s)\n}\n", rawJSON(fields))
+	}
+	src, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid go code: %w", err)
+	}
+	return src, nil
+}
+
+// exportRawFields returns the dashboard fields which differ from the builder result, like annotations, links or the timepicker.
+// Builder defaults missing in the dashboard are returned as null.
+func exportRawFields(b *dashboard.DashboardBuilder, d map[string]any) (map[string]any, error) {
+	built, err := b.Build()
+	if err != nil {
+		return nil, fmt.Errorf("unable to build dashboard: %w", err)
+	}
+	generic, _ := jsonValue(built).(map[string]any)
+	res := map[string]any{}
+	for k, v := range d {
+		if slices.Contains(volatileDashboardFields, k) || k == "templating" || k == "panels" {
+			continue
+		}
+		if !reflect.DeepEqual(generic[k], jsonValue(v)) {
+			res[k] = v
+		}
+	}
+	for k := range generic {
+		if _, ok := d[k]; !ok && k != "templating" && k != "panels" {
+			res[k] = nil
+		}
+	}
+	return res, nil
+}
+
+func (e *dashboardExport) exportVariable(v map[string]any) {
+	var code string
+	ok := e.attempt(func(e *dashboardExport) bool {
+		var b cog.Builder[dashboard.VariableModel]
+		code, b = e.variableBuilder(v)
+		return b != nil && sameBuild(b, cog.Builder[dashboard.VariableModel](rawBuilder[dashboard.VariableModel](formatDiffValue(v))))
+	})
+	if !ok {
+		code = fmt.Sprintf("rawVariable(%s)", rawJSON(v))
+	}
+	e.line("WithVariable(%s).", code)
+}
+
+// variableBuilder returns the go code of a variable builder and the builder itself, nil if there is no builder for the variable
+func (e *dashboardExport) variableBuilder(v map[string]any) (string, cog.Builder[dashboard.VariableModel]) {
+	name, _ := v["name"].(string)
+	label, _ := v["label"].(string)
+	q, _ := v["query"].(string)
+	multi, _ := v["multi"].(bool)
+	includeAll, _ := v["includeAll"].(bool)
+	switch v["type"] {
+	case "query":
+		dsCode, ds, ok := e.datasourceRef(v["datasource"])
+		if _, isString := v["query"].(string); !isString || !ok {
+			return "", nil
+		}
+		allSelected := false
+		if current, isMap := v["current"].(map[string]any); isMap {
+			allSelected = slices.Contains(stringList(current["value"]), "$__all") || current["value"] == "$__all"
+		}
+		e.use(repoImportPrefix + "query")
+		return fmt.Sprintf("query.QueryVariable(%q, %q, %q, %s, %t, %t, %t)", name, label, q, dsCode, includeAll, allSelected, multi),
+			query.QueryVariable(name, label, q, ds, includeAll, allSelected, multi)
+	case "custom":
+		e.use(sdkImportPrefix + "cog")
+		b := dashboard.NewCustomVariableBuilder(name)
+		code := fmt.Sprintf("dashboard.NewCustomVariableBuilder(%q)", name)
+		if label != "" {
+			b.Label(label)
+			code += fmt.Sprintf(".Label(%q)", label)
+		}
+		b.Values(dashboard.StringOrMap{String: cog.ToPtr(q)}).Multi(multi).IncludeAll(includeAll)
+		return code + fmt.Sprintf(".Values(dashboard.StringOrMap{String: cog.ToPtr(%q)}).Multi(%t).IncludeAll(%t)", q, multi, includeAll), b
+	case "interval":
+		e.use(sdkImportPrefix + "cog")
+		b := dashboard.NewIntervalVariableBuilder(name)
+		code := fmt.Sprintf("dashboard.NewIntervalVariableBuilder(%q)", name)
+		if label != "" {
+			b.Label(label)
+			code += fmt.Sprintf(".Label(%q)", label)
+		}
+		b.Values(dashboard.StringOrMap{String: cog.ToPtr(q)})
+		return code + fmt.Sprintf(".Values(dashboard.StringOrMap{String: cog.ToPtr(%q)})", q), b
+	case "constant":
+		e.use(sdkImportPrefix + "cog")
+		b := dashboard.NewConstantVariableBuilder(name).Value(dashboard.StringOrMap{String: cog.ToPtr(q)})
+		return fmt.Sprintf("dashboard.NewConstantVariableBuilder(%q).Value(dashboard.StringOrMap{String: cog.ToPtr(%q)})", name, q), b
+	case "textbox":
+		e.use(sdkImportPrefix + "cog")
+		b := dashboard.NewTextBoxVariableBuilder(name)
+		code := fmt.Sprintf("dashboard.NewTextBoxVariableBuilder(%q)", name)
+		if label != "" {
+			b.Label(label)
+			code += fmt.Sprintf(".Label(%q)", label)
+		}
+		b.DefaultValue(dashboard.StringOrMap{String: cog.ToPtr(q)})
+		return code + fmt.Sprintf(".DefaultValue(dashboard.StringOrMap{String: cog.ToPtr(%q)})", q), b
+	case "datasource":
+		b := dashboard.NewDatasourceVariableBuilder(name)
+		code := fmt.Sprintf("dashboard.NewDatasourceVariableBuilder(%q)", name)
+		if label != "" {
+			b.Label(label)
+			code += fmt.Sprintf(".Label(%q)", label)
+		}
+		b.Type(q).Multi(multi).IncludeAll(includeAll)
+		return code + fmt.Sprintf(".Type(%q).Multi(%t).IncludeAll(%t)", q, multi, includeAll), b
+	}
+	return "", nil
+}
+
+func (e *dashboardExport) exportRow(row map[string]any) {
+	var code string
+	ok := e.attempt(func(e *dashboardExport) bool {
+		title, _ := row["title"].(string)
+		b := dashboard.NewRowBuilder(title)
+		code = fmt.Sprintf("dashboard.NewRowBuilder(%q)", title)
+		if v, ok := row["id"].(float64); ok {
+			b.Id(uint32(v))
+			code += fmt.Sprintf(".Id(%d)", uint32(v))
+		}
+		if gpCode, gp, ok := gridPos(row["gridPos"]); ok {
+			b.GridPos(gp)
+			code += ".GridPos(" + gpCode + ")"
+		}
+		if collapsed, _ := row["collapsed"].(bool); collapsed {
+			b.Collapsed(true)
+			code += ".Collapsed(true)"
+		}
+		panels, _ := row["panels"].([]any)
+		for _, p := range panels {
+			m, ok := p.(map[string]any)
+			if !ok {
+				return false
+			}
+			panelCode, panel := e.panelBuilder(m)
+			b.WithPanel(panel)
+			code += fmt.Sprintf(".\n\t\t\tWithPanel(%s)", panelCode)
+		}
+		return sameBuild(b, cog.Builder[dashboard.RowPanel](rawBuilder[dashboard.RowPanel](formatDiffValue(row))))
+	})
+	if !ok {
+		code = fmt.Sprintf("rawRow(%s)", rawJSON(row))
+	}
+	e.line("WithRow(%s).", code)
+}
+
+// panelBuilder returns the go code of a panel and its builder. Panels the builders can not reproduce exactly are exported as raw json.
+func (e *dashboardExport) panelBuilder(p map[string]any) (string, cog.Builder[dashboard.Panel]) {
+	raw := rawBuilder[dashboard.Panel](formatDiffValue(p))
+	var code string
+	var b cog.Builder[dashboard.Panel]
+	ok := e.attempt(func(e *dashboardExport) bool {
+		var built bool
+		code, b, built = e.typedPanelBuilder(p)
+		return built && sameBuild(b, cog.Builder[dashboard.Panel](raw))
+	})
+	if !ok {
+		return fmt.Sprintf("rawPanel(%s)", rawJSON(p)), raw
+	}
+	return code, b
+}
+
+func (e *dashboardExport) typedPanelBuilder(p map[string]any) (string, cog.Builder[dashboard.Panel], bool) {
+	if lib, ok := p["libraryPanel"].(map[string]any); ok {
+		uid, _ := lib["uid"].(string)
+		name, _ := lib["name"].(string)
+		b := dashboard.NewPanelBuilder().LibraryPanel(dashboard.LibraryPanelRef{Uid: uid, Name: name})
+		code := fmt.Sprintf("dashboard.NewPanelBuilder().LibraryPanel(dashboard.LibraryPanelRef{Uid: %q, Name: %q})", uid, name)
+		if v, ok := p["id"].(float64); ok {
+			b.Id(uint32(v))
+			code += fmt.Sprintf(".Id(%d)", uint32(v))
+		}
+		if v, ok := p["title"].(string); ok {
+			b.Title(v)
+			code += fmt.Sprintf(".Title(%q)", v)
+		}
+		if gpCode, gp, ok := gridPos(p["gridPos"]); ok {
+			b.GridPos(gp)
+			code += ".GridPos(" + gpCode + ")"
+		}
+		return code, b, true
+	}
+	if !isPrometheusRef(p["datasource"]) {
+		return "", nil, false
+	}
+	switch p["type"] {
+	case "timeseries":
+		return exportPanel(e, "timeseries", timeseries.NewPanelBuilder(), p)
+	case "stat":
+		return exportPanel(e, "stat", stat.NewPanelBuilder(), p)
+	case "gauge":
+		return exportPanel(e, "gauge", gauge.NewPanelBuilder(), p)
+	case "bargauge":
+		return exportPanel(e, "bargauge", bargauge.NewPanelBuilder(), p)
+	case "table":
+		return exportPanel(e, "table", table.NewPanelBuilder(), p)
+	case "text":
+		return exportPanel(e, "text", text.NewPanelBuilder(), p)
+	case "piechart":
+		return exportPanel(e, "piechart", piechart.NewPanelBuilder(), p)
+	}
+	return "", nil, false
+}
+
+// exportablePanel are the methods all panel builders of the export share
+type exportablePanel[T any] interface {
+	cog.Builder[dashboard.Panel]
+	Id(id uint32) T
+	Title(title string) T
+	Description(description string) T
+	Datasource(datasource dashboard.DataSourceRef) T
+	GridPos(gridPos dashboard.GridPos) T
+	Interval(interval string) T
+	Transparent(transparent bool) T
+	Unit(unit string) T
+	Decimals(decimals float64) T
+	Min(min float64) T
+	Max(max float64) T
+	WithTarget(target cog.Builder[variants.Dataquery]) T
+	WithOverride(matcher dashboard.MatcherConfig, properties []dashboard.DynamicConfigValue) T
+}
+
+// exportPanel generates the builder chain of the panel package and calls it on b
+func exportPanel[T exportablePanel[T]](e *dashboardExport, panelType string, b T, p map[string]any) (string, cog.Builder[dashboard.Panel], bool) {
+	e.use(sdkImportPrefix + panelType)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s.NewPanelBuilder()", panelType)
+	chain := func(format string, args ...any) {
+		fmt.Fprintf(&sb, ".\n\t\t\t"+format, args...)
+	}
+	if v, ok := p["id"].(float64); ok {
+		chain("Id(%d)", uint32(v))
+		b.Id(uint32(v))
+	}
+	if v, ok := p["title"].(string); ok {
+		chain("Title(%q)", v)
+		b.Title(v)
+	}
+	if v, ok := p["description"].(string); ok {
+		chain("Description(%q)", v)
+		b.Description(v)
+	}
+	if p["datasource"] != nil {
+		code, ds, ok := e.datasourceRef(p["datasource"])
+		if !ok {
+			return "", nil, false
+		}
+		chain("Datasource(%s)", code)
+		b.Datasource(ds)
+	}
+	if code, gp, ok := gridPos(p["gridPos"]); ok {
+		chain("GridPos(%s)", code)
+		b.GridPos(gp)
+	}
+	if v, ok := p["interval"].(string); ok {
+		chain("Interval(%q)", v)
+		b.Interval(v)
+	}
+	if v, ok := p["transparent"].(bool); ok {
+		chain("Transparent(%t)", v)
+		b.Transparent(v)
+	}
+
+	fieldConfig, _ := p["fieldConfig"].(map[string]any)
+	defaults, _ := fieldConfig["defaults"].(map[string]any)
+	if v, ok := defaults["unit"].(string); ok {
+		chain("Unit(%s)", e.unitExpr(v))
+		b.Unit(v)
+	}
+	if v, ok := defaults["decimals"].(float64); ok {
+		chain("Decimals(%s)", goLiteral(v))
+		b.Decimals(v)
+	}
+	if v, ok := defaults["min"].(float64); ok {
+		chain("Min(%s)", goLiteral(v))
+		b.Min(v)
+	}
+	if v, ok := defaults["max"].(float64); ok {
+		chain("Max(%s)", goLiteral(v))
+		b.Max(v)
+	}
+	if tb, ok := any(b).(*text.PanelBuilder); ok {
+		options, _ := p["options"].(map[string]any)
+		if v, ok := options["mode"].(string); ok {
+			chain("Mode(text.TextMode(%q))", v)
+			tb.Mode(text.TextMode(v))
+		}
+		if v, ok := options["content"].(string); ok {
+			chain("Content(%q)", v)
+			tb.Content(v)
+		}
+	}
+	list, _ := p["targets"].([]any)
+	for _, t := range list {
+		m, ok := t.(map[string]any)
+		if !ok {
+			return "", nil, false
+		}
+		code, target, ok := e.prometheusTarget(m)
+		if !ok {
+			return "", nil, false
+		}
+		chain("WithTarget(%s)", code)
+		b.WithTarget(target)
+	}
+	overrides, _ := fieldConfig["overrides"].([]any)
+	for _, o := range overrides {
+		m, ok := o.(map[string]any)
+		if !ok {
+			return "", nil, false
+		}
+		matcherCode, matcher := e.overrideMatcher(m["matcher"])
+		propertiesCode, properties := e.overrideProperties(m["properties"])
+		chain("WithOverride(%s, %s)", matcherCode, propertiesCode)
+		b.WithOverride(matcher, properties)
+	}
+	return sb.String(), b, true
+}
+
+func (e *dashboardExport) prometheusTarget(t map[string]any) (string, *prometheus.DataqueryBuilder, bool) {
+	expr, ok := t["expr"].(string)
+	if !ok || !isPrometheusRef(t["datasource"]) {
+		return "", nil, false
+	}
+	refID, _ := t["refId"].(string)
+	legend, _ := t["legendFormat"].(string)
+	e.use(repoImportPrefix + "query")
+	var res string
+	var b *prometheus.DataqueryBuilder
+	if t["format"] == "table" {
+		res = fmt.Sprintf("query.TablePrometheusQuery(%q, %q)", expr, refID)
+		b = query.TablePrometheusQuery(expr, refID)
+		if legend != "" {
+			res += fmt.Sprintf(".LegendFormat(%q)", legend)
+			b.LegendFormat(legend)
+		}
+	} else {
+		res = fmt.Sprintf("query.PrometheusQuery(%q, %q)", expr, legend)
+		b = query.PrometheusQuery(expr, legend)
+		if refID != "" {
+			res += fmt.Sprintf(".RefId(%q)", refID)
+			b.RefId(refID)
+		}
+	}
+	if v, ok := t["instant"].(bool); ok && v {
+		res += ".Instant()"
+		b.Instant()
+	}
+	if v, ok := t["interval"].(string); ok && v != "" {
+		res += fmt.Sprintf(".Interval(%q)", v)
+		b.Interval(v)
+	}
+	if v, ok := t["hide"].(bool); ok && v {
+		res += ".Hide(true)"
+		b.Hide(true)
+	}
+	if code, ds, ok := e.datasourceRef(t["datasource"]); ok {
+		res += fmt.Sprintf(".Datasource(%s)", code)
+		b.Datasource(ds)
+	}
+	return res, b, true
+}
+
+// datasourceRef returns the go code of a datasource reference and the reference itself. Variables are referenced with the variable package.
+func (e *dashboardExport) datasourceRef(v any) (string, dashboard.DataSourceRef, bool) {
+	var uid, dsType string
+	switch t := v.(type) {
+	case string:
+		uid = t
+	case map[string]any:
+		uid, _ = t["uid"].(string)
+		dsType, _ = t["type"].(string)
+	}
+	if uid == "" {
+		return "", dashboard.DataSourceRef{}, false
+	}
+	if strings.HasPrefix(uid, "$") {
+		e.use(repoImportPrefix + "variable")
+		name := strings.Trim(uid, "${}")
+		return fmt.Sprintf("variable.DashboardConstant(%q).AsRef()", name), variable.DashboardConstant(name).AsRef(), true
+	}
+	e.use(sdkImportPrefix + "cog")
+	if dsType == "" {
+		return fmt.Sprintf("dashboard.DataSourceRef{Uid: cog.ToPtr(%q)}", uid), dashboard.DataSourceRef{Uid: cog.ToPtr(uid)}, true
+	}
+	return fmt.Sprintf("dashboard.DataSourceRef{Type: cog.ToPtr(%q), Uid: cog.ToPtr(%q)}", dsType, uid), dashboard.DataSourceRef{Type: cog.ToPtr(dsType), Uid: cog.ToPtr(uid)}, true
+}
+
+func (e *dashboardExport) unitExpr(u string) string {
+	if c, ok := exportUnits[u]; ok {
+		e.use(repoImportPrefix + "unit")
+		return "unit." + c
+	}
+	return strconv.Quote(u)
+}
+
+func (e *dashboardExport) overrideMatcher(v any) (string, dashboard.MatcherConfig) {
+	m, _ := v.(map[string]any)
+	id, _ := m["id"].(string)
+	option, isString := m["options"].(string)
+	if isString {
+		helper := map[string]string{"byName": "ByName", "byRegexp": "ByRegex", "byFrameRefID": "ByQuery"}[id]
+		if helper != "" {
+			e.use(repoImportPrefix + "override")
+			return fmt.Sprintf("override.%s(%q)", helper, option), dashboard.MatcherConfig{Id: id, Options: option}
+		}
+		if id == "byType" {
+			e.use(repoImportPrefix + "override")
+			return fmt.Sprintf("override.ByType(override.MatcherConfigFieldType(%q))", option), override.ByType(override.MatcherConfigFieldType(option))
+		}
+	}
+	return fmt.Sprintf("dashboard.MatcherConfig{Id: %q, Options: %s}", id, goLiteral(m["options"])), dashboard.MatcherConfig{Id: id, Options: m["options"]}
+}
+
+func (e *dashboardExport) overrideProperties(v any) (string, []dashboard.DynamicConfigValue) {
+	list, _ := v.([]any)
+	var code []string
+	var res []dashboard.DynamicConfigValue
+	for _, p := range list {
+		m, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+		c, property := e.overrideProperty(m)
+		code = append(code, c)
+		res = append(res, property)
+	}
+	return "[]dashboard.DynamicConfigValue{" + strings.Join(code, ", ") + "}", res
+}
+
+func (e *dashboardExport) overrideProperty(p map[string]any) (string, dashboard.DynamicConfigValue) {
+	id, _ := p["id"].(string)
+	value := p["value"]
+	helper := ""
+	var res dashboard.DynamicConfigValue
+	switch id {
+	case "unit":
+		if s, ok := value.(string); ok {
+			helper = fmt.Sprintf("override.Unit(%s)", e.unitExpr(s))
+			res = override.Unit(s)
+		}
+	case "custom.fillOpacity":
+		if f, ok := value.(float64); ok && f == float64(int(f)) {
+			helper = fmt.Sprintf("override.FillOpacity(%d)", int(f))
+			res = override.FillOpacity(int(f))
+		}
+	case "custom.transform":
+		if value == "negative-Y" {
+			helper = "override.NegativeY()"
+			res = override.NegativeY()
+		}
+	case "custom.axisPlacement":
+		if s, ok := value.(string); ok {
+			helper = fmt.Sprintf("override.AxisPlacement(override.PlacementMode(%q))", s)
+			res = override.AxisPlacement(override.PlacementMode(s))
+		}
+	case "custom.stacking":
+		if m, ok := value.(map[string]any); ok && len(m) == 2 && m["group"] == false {
+			if mode, ok := m["mode"].(string); ok {
+				helper = fmt.Sprintf("override.Stack(override.StackMode(%q))", mode)
+				res = override.Stack(override.StackMode(mode))
+			}
+		}
+	case "color":
+		if m, ok := value.(map[string]any); ok && len(m) == 2 && m["mode"] == "fixed" {
+			if color, ok := m["fixedColor"].(string); ok {
+				helper = fmt.Sprintf("override.FixedColorScheme(%q)", color)
+				res = override.FixedColorScheme(color)
+			}
+		}
+	}
+	if helper != "" {
+		e.use(repoImportPrefix + "override")
+		return helper, res
+	}
+	return fmt.Sprintf("{Id: %q, Value: %s}", id, goLiteral(value)), dashboard.DynamicConfigValue{Id: id, Value: value}
+}
+
+// isPrometheusRef is false for datasource references of an other type than prometheus, the query helpers only build prometheus queries
+func isPrometheusRef(v any) bool {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return true
+	}
+	dsType, _ := m["type"].(string)
+	return dsType == "" || dsType == "prometheus"
+}
+
+func gridPos(v any) (string, dashboard.GridPos, bool) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return "", dashboard.GridPos{}, false
+	}
+	var parts []string
+	var res dashboard.GridPos
+	fields := map[string]*uint32{"h": &res.H, "w": &res.W, "x": &res.X, "y": &res.Y}
+	for _, k := range []string{"h", "w", "x", "y"} {
+		if f, ok := m[k].(float64); ok {
+			parts = append(parts, fmt.Sprintf("%s: %d", strings.ToUpper(k), uint32(f)))
+			*fields[k] = uint32(f)
+		}
+	}
+	return "dashboard.GridPos{" + strings.Join(parts, ", ") + "}", res, true
+}
+
+func stringList(v any) []string {
+	list, _ := v.([]any)
+	var res []string
+	for _, e := range list {
+		if s, ok := e.(string); ok {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func rawJSON(v any) string {
+	return "`" + strings.ReplaceAll(formatDiffValue(v), "`", "` + \"`\" + `") + "`"
+}
+
+// goLiteral returns the go code of a generic json value
+func goLiteral(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(t)
+	case bool:
+		return strconv.FormatBool(t)
+	case float64:
+		return strconv.FormatFloat(t, 'g', -1, 64)
+	case []string:
+		parts := make([]string, 0, len(t))
+		for _, e := range t {
+			parts = append(parts, strconv.Quote(e))
+		}
+		return "[]string{" + strings.Join(parts, ", ") + "}"
+	case []any:
+		parts := make([]string, 0, len(t))
+		for _, e := range t {
+			parts = append(parts, goLiteral(e))
+		}
+		return "[]any{" + strings.Join(parts, ", ") + "}"
+	case map[string]any:
+		parts := make([]string, 0, len(t))
+		for _, k := range sortedKeys(t) {
+			parts = append(parts, fmt.Sprintf("%q: %s", k, goLiteral(t[k])))
+		}
+		return "map[string]any{" + strings.Join(parts, ", ") + "}"
+	}
+	return fmt.Sprintf("%#v", v)
+}
@@ -0,0 +1,266 @@
+package grafanasdkclistarter
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fasibio/grafanaSdkCliStarter/query"
+	"github.com/fasibio/grafanaSdkCliStarter/unit"
+	"github.com/grafana/grafana-foundation-sdk/go/cog"
+	"github.com/grafana/grafana-foundation-sdk/go/dashboard"
+	"github.com/grafana/grafana-foundation-sdk/go/stat"
+	"github.com/grafana/grafana-foundation-sdk/go/text"
+	"github.com/grafana/grafana-foundation-sdk/go/timeseries"
+)
+
+// grafanaDashboard is a dashboard like grafana saves it after editing in the ui
+const grafanaDashboard = `{
+  "annotations": {"list": [{"builtIn": 1, "datasource": {"type": "grafana", "uid": "-- Grafana --"}, "enable": true, "hide": true, "iconColor": "rgba(0, 211, 255, 1)", "name": "Annotations & Alerts", "type": "dashboard"}]},
+  "description": "api overview",
+  "editable": true,
+  "fiscalYearStartMonth": 0,
+  "graphTooltip": 1,
+  "id": 12,
+  "links": [{"asDropdown": false, "icon": "external link", "includeVars": true, "keepTime": true, "tags": ["api"], "targetBlank": false, "title": "api", "type": "dashboards", "url": ""}],
+  "panels": [
+    {
+      "datasource": {"type": "prometheus", "uid": "prometheus"},
+      "fieldConfig": {
+        "defaults": {
+          "color": {"mode": "palette-classic"},
+          "mappings": [],
+          "thresholds": {"mode": "absolute", "steps": [{"color": "green", "value": null}, {"color": "red", "value": 80}]},
+          "unit": "reqps"
+        },
+        "overrides": [{"matcher": {"id": "byName", "options": "errors"}, "properties": [{"id": "color", "value": {"fixedColor": "red", "mode": "fixed"}}]}]
+      },
+      "gridPos": {"h": 8, "w": 12, "x": 0, "y": 0},
+      "id": 1,
+      "options": {"legend": {"calcs": [], "displayMode": "list", "placement": "bottom", "showLegend": true}, "tooltip": {"mode": "single", "sort": "none"}},
+      "targets": [{"datasource": {"type": "prometheus", "uid": "prometheus"}, "editorMode": "code", "expr": "sum(rate(http_requests_total[$__rate_interval]))", "legendFormat": "requests", "range": true, "refId": "A"}],
+      "title": "Requests",
+      "type": "timeseries"
+    },
+    {
+      "gridPos": {"h": 4, "w": 12, "x": 12, "y": 0},
+      "id": 2,
+      "options": {"content": "# Api", "mode": "markdown"},
+      "title": "Info",
+      "transparent": false,
+      "type": "text"
+    },
+    {
+      "collapsed": true,
+      "gridPos": {"h": 1, "w": 24, "x": 0, "y": 8},
+      "id": 3,
+      "panels": [
+        {
+          "datasource": {"type": "loki", "uid": "loki"},
+          "gridPos": {"h": 8, "w": 24, "x": 0, "y": 9},
+          "id": 4,
+          "options": {"dedupStrategy": "none", "showTime": true},
+          "targets": [{"datasource": {"type": "loki", "uid": "loki"}, "expr": "{app=\"api\"}", "refId": "A"}],
+          "title": "Logs",
+          "type": "logs"
+        }
+      ],
+      "title": "Details",
+      "type": "row"
+    },
+    {"collapsed": false, "gridPos": {"h": 1, "w": 24, "x": 0, "y": 9}, "id": 5, "panels": [], "repeat": "env", "title": "$env", "type": "row"}
+  ],
+  "refresh": "30s",
+  "schemaVersion": 39,
+  "tags": ["api"],
+  "templating": {"list": [
+    {
+      "current": {"selected": false, "text": "prod", "value": "prod"},
+      "datasource": {"type": "prometheus", "uid": "prometheus"},
+      "definition": "label_values(up, env)",
+      "hide": 0,
+      "includeAll": false,
+      "multi": false,
+      "name": "env",
+      "options": [],
+      "query": {"query": "label_values(up, env)", "refId": "PrometheusVariableQueryEditor-VariableQuery"},
+      "refresh": 1,
+      "regex": "",
+      "skipUrlSync": false,
+      "sort": 0,
+      "type": "query"
+    },
+    {"name": "quantile", "query": "0.5,0.9,0.99", "type": "custom"}
+  ]},
+  "time": {"from": "now-6h", "to": "now"},
+  "timepicker": {"refresh_intervals": ["10s", "30s", "1m"]},
+  "timezone": "",
+  "title": "Api",
+  "uid": "api",
+  "version": 4,
+  "weekStart": ""
+}`
+
+// codeDashboard returns a dashboard like this cli creates it
+func codeDashboard(t *testing.T) map[string]any {
+	t.Helper()
+	d, err := dashboard.NewDashboardBuilder("Code").
+		Uid("code").
+		Tags([]string{"code"}).
+		Time("now-1h", "now").
+		WithVariable(query.QueryVariable("job", "Job", "label_values(up, job)", dashboard.DataSourceRef{Uid: cog.ToPtr("prometheus")}, true, true, true)).
+		WithVariable(dashboard.NewCustomVariableBuilder("quantile").Values(dashboard.StringOrMap{String: cog.ToPtr("0.5,0.9")}).Multi(false).IncludeAll(false)).
+		WithPanel(stat.NewPanelBuilder().
+			Title("Up").
+			Unit(unit.PercentUnit).
+			GridPos(dashboard.GridPos{H: 4, W: 6}).
+			WithTarget(query.PrometheusQuery("avg(up)", "up").RefId("A"))).
+		WithPanel(timeseries.NewPanelBuilder().
+			Title("Latency").
+			Decimals(2).
+			GridPos(dashboard.GridPos{H: 8, W: 18, X: 6}).
+			WithTarget(query.PrometheusQuery("histogram_quantile(0.9, rate(latency_bucket[5m]))", "p90").Instant())).
+		WithRow(dashboard.NewRowBuilder("Text").
+			Collapsed(true).
+			WithPanel(text.NewPanelBuilder().Content("hello").GridPos(dashboard.GridPos{H: 2, W: 24, Y: 9}))).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := normalizeDashboard(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+// roundTrip exports the dashboard, compiles and runs the generated code and returns the source and the dashboard it builds
+func roundTrip(t *testing.T, d map[string]any) (string, map[string]any) {
+	t.Helper()
+	src, err := exportDashboard("gen", "ExportedDashboard", d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	helpers, err := exportDashboards("gen", []string{"ExportedDashboard"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the generated code imports packages of this module, so it has to be compiled inside of it
+	dir, err := os.MkdirTemp(".", "export-roundtrip-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	gen := filepath.Join(dir, "gen")
+	if err := os.Mkdir(gen, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	main := `package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"` + repoImportPrefix + filepath.Base(dir) + `/gen"
+)
+
+func main() {
+	d, err := gen.Dashboards("", nil)
+	if err != nil {
+		panic(err)
+	}
+	json.NewEncoder(os.Stdout).Encode(d[0])
+}
+`
+	for file, content := range map[string][]byte{
+		filepath.Join(gen, "exported.go"):   src,
+		filepath.Join(gen, "dashboards.go"): helpers,
+		filepath.Join(dir, "main.go"):       []byte(main),
+	} {
+		if err := os.WriteFile(file, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err := exec.Command("go", "run", "./"+filepath.Base(dir)).CombinedOutput()
+	if err != nil {
+		t.Fatalf("generated code failed: %v\n%s\n%s", err, out, src)
+	}
+	var built any
+	if err := json.Unmarshal(out, &built); err != nil {
+		t.Fatal(err)
+	}
+	res, err := normalizeDashboard(built)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(src), res
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles the generated code")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+	var fromGrafana map[string]any
+	if err := json.Unmarshal([]byte(grafanaDashboard), &fromGrafana); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		dashboard map[string]any
+		// sdkLimits are the differences of fields the foundation sdk types do not keep, they are lost on every apply
+		sdkLimits []string
+		// contains and excludes are checked against the generated code
+		contains []string
+		excludes []string
+	}{
+		{
+			name:      "edited in grafana",
+			dashboard: fromGrafana,
+			sdkLimits: []string{
+				`+ links[0].tooltip: ""`,
+				`- panels[0].fieldConfig.defaults.mappings: []`,
+				`- panels[3].panels: []`,
+				`- templating.list[0].definition: "label_values(up, env)"`,
+				`- templating.list[0].options: []`,
+			},
+			contains: []string{"text.NewPanelBuilder()", "rawPanel(", "rawRow(", "rawVariable(", "dashboard.NewRowBuilder(\"Details\")", "return rawFields(d, "},
+		},
+		{
+			name:      "created by code",
+			dashboard: codeDashboard(t),
+			contains:  []string{"query.QueryVariable(", "dashboard.NewCustomVariableBuilder(", "stat.NewPanelBuilder()", "timeseries.NewPanelBuilder()", "Unit(unit.PercentUnit)", ".Instant()", "dashboard.NewRowBuilder(\"Text\")"},
+			excludes:  []string{"rawPanel(", "rawRow(", "rawVariable(", "rawFields("},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, built := roundTrip(t, tt.dashboard)
+			want, err := normalizeDashboard(tt.dashboard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, d := range diffValues("", want, built) {
+				if !slices.Contains(tt.sdkLimits, d.String()) {
+					t.Errorf("applying the export changes the dashboard: %s", d)
+				}
+			}
+			for _, c := range tt.contains {
+				if !strings.Contains(src, c) {
+					t.Errorf("generated code does not contain %s:\n%s", c, src)
+				}
+			}
+			for _, c := range tt.excludes {
+				if strings.Contains(src, c) {
+					t.Errorf("generated code contains %s:\n%s", c, src)
+				}
+			}
+		})
+	}
+}
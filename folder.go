@@ -0,0 +1,129 @@
+package grafanasdkclistarter
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana-foundation-sdk/go/dashboard"
+	"github.com/grafana/grafana-openapi-client-go/models"
+)
+
+const (
+	// FolderPathSeparator separates the folder titles of a nested folder path like Platform/Payments/Latency
+	FolderPathSeparator = "/"
+	// maxUIDLength is the longest uid grafana accepts
+	maxUIDLength = 40
+)
+
+// FolderDashboard is a dashboard placed into a (nested) folder.
+type FolderDashboard struct {
+	// Folder is a path like Platform/Payments/Latency relative to the foldername flag
+	Folder    string
+	Dashboard dashboard.Dashboard
+}
+
+// JoinFolderPath joins folder paths and removes empty segments
+func JoinFolderPath(paths ...string) string {
+	var segments []string
+	for _, p := range paths {
+		segments = append(segments, splitFolderPath(p)...)
+	}
+	return strings.Join(segments, FolderPathSeparator)
+}
+
+func splitFolderPath(p string) []string {
+	var res []string
+	for _, s := range strings.Split(p, FolderPathSeparator) {
+		s = strings.TrimSpace(s)
+		if s != "" {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// FolderUID returns the stable uid of a folder path. Every level gets its parent uid as prefix,
+// so a single level folder keeps its name as uid if it is a valid uid already.
+func FolderUID(path string) string {
+	uid := ""
+	for _, s := range splitFolderPath(path) {
+		uid = joinUID(uid, slugUID(s))
+	}
+	return uid
+}
+
+func joinUID(parent, slug string) string {
+	uid := slug
+	if parent != "" {
+		uid = parent + "-" + slug
+	}
+	if len(uid) <= maxUIDLength {
+		return uid
+	}
+	sum := sha1.Sum([]byte(uid))
+	hash := hex.EncodeToString(sum[:])[:8]
+	return strings.TrimRight(uid[:maxUIDLength-len(hash)-1], "-") + "-" + hash
+}
+
+// slugUID keeps letters, digits, - and _ and replaces everything else by -
+func slugUID(title string) string {
+	var sb strings.Builder
+	lastDash := false
+	for _, r := range title {
+		valid := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-'
+		if valid {
+			sb.WriteRune(r)
+			lastDash = r == '-'
+			continue
+		}
+		if !lastDash {
+			sb.WriteRune('-')
+			lastDash = true
+		}
+	}
+	res := strings.Trim(sb.String(), "-")
+	if res == "" {
+		sum := sha1.Sum([]byte(title))
+		return hex.EncodeToString(sum[:])[:8]
+	}
+	return res
+}
+
+// ensureFolderPath creates all missing folders of the path and returns the uid of the last one
+func (r *Runner) ensureFolderPath(path string) (string, error) {
+	parent := ""
+	for _, title := range splitFolderPath(path) {
+		uid := joinUID(parent, slugUID(title))
+		if r.knownFolders[uid] {
+			parent = uid
+			continue
+		}
+		_, err := r.client.Folders.GetFolderByUID(uid)
+		if err != nil {
+			_, err := r.client.Folders.CreateFolder(&models.CreateFolderCommand{
+				UID:       uid,
+				Title:     title,
+				ParentUID: parent,
+			})
+			if err != nil {
+				return "", fmt.Errorf("apply: can not create folder %s: %w", title, err)
+			}
+		}
+		if r.knownFolders == nil {
+			r.knownFolders = map[string]bool{}
+		}
+		r.knownFolders[uid] = true
+		parent = uid
+	}
+	return parent, nil
+}
+
+func folderDashboards(dashboards []FolderDashboard) []dashboard.Dashboard {
+	res := make([]dashboard.Dashboard, 0, len(dashboards))
+	for _, d := range dashboards {
+		res = append(res, d.Dashboard)
+	}
+	return res
+}
@@ -0,0 +1,90 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFolderUID(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "root", path: "", want: ""},
+		{name: "single level keeps its name", path: "Platform", want: "Platform"},
+		{name: "nested levels get the parent as prefix", path: "Platform/Payments", want: "Platform-Payments"},
+		{name: "empty segments are ignored", path: "/Platform// Payments /", want: "Platform-Payments"},
+		{name: "invalid characters become one dash", path: "Team A & B", want: "Team-A-B"},
+		{name: "no valid character is hashed", path: "äöü", want: "d19dbe94"},
+		{name: "exactly 40 characters", path: strings.Repeat("a", 40), want: strings.Repeat("a", 40)},
+		{name: "longer than 40 characters is hashed", path: strings.Repeat("a", 41), want: strings.Repeat("a", 31) + "-52cedd6b"},
+		{name: "nested path longer than 40 characters", path: strings.Repeat("a", 20) + "/" + strings.Repeat("b", 20), want: strings.Repeat("a", 20) + "-" + strings.Repeat("b", 10) + "-19b9d40b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FolderUID(tt.path)
+			if got != tt.want {
+				t.Errorf("FolderUID(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+			if len(got) > maxUIDLength {
+				t.Errorf("FolderUID(%q) has %d characters, grafana accepts %d", tt.path, len(got), maxUIDLength)
+			}
+		})
+	}
+}
+
+func TestFolderUIDIsStableAndUnique(t *testing.T) {
+	a := FolderUID(strings.Repeat("x", 50) + "/a")
+	b := FolderUID(strings.Repeat("x", 50) + "/b")
+	if a == b {
+		t.Errorf("long paths with different leafs share the uid %s", a)
+	}
+	if a != FolderUID(strings.Repeat("x", 50)+"/a") {
+		t.Errorf("uid of the same path changed")
+	}
+}
+
+func TestJoinFolderPath(t *testing.T) {
+	tests := []struct {
+		paths []string
+		want  string
+	}{
+		{paths: nil, want: ""},
+		{paths: []string{"", "Payments"}, want: "Payments"},
+		{paths: []string{"Platform/", "/Payments/Latency"}, want: "Platform/Payments/Latency"},
+	}
+	for _, tt := range tests {
+		if got := JoinFolderPath(tt.paths...); got != tt.want {
+			t.Errorf("JoinFolderPath(%q) = %q, want %q", tt.paths, got, tt.want)
+		}
+	}
+}
+
+func TestFolderTree(t *testing.T) {
+	children := map[string][]string{
+		"root":   {"root-a", "root-b"},
+		"root-a": {"root-a-x"},
+	}
+	r := fakeGrafana(t, func(path string, query url.Values) any {
+		if path != "/api/folders" {
+			return nil
+		}
+		hits := []map[string]string{}
+		for _, uid := range children[query.Get("parentUid")] {
+			hits = append(hits, map[string]string{"uid": uid, "title": uid})
+		}
+		return hits
+	})
+	got, err := r.folderTree(context.Background(), "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"root", "root-a", "root-b", "root-a-x"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("folderTree() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,53 @@
+package grafanasdkclistarter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-openapi/strfmt"
+	goapi "github.com/grafana/grafana-openapi-client-go/client"
+)
+
+// fakeGrafanaURL serves the json answers of handler and returns the url of the server, a nil answer is a 404
+func fakeGrafanaURL(t *testing.T, handler func(r *http.Request) any) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := handler(r)
+		w.Header().Set("Content-Type", "application/json")
+		if res == nil {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"not found"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(res)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// fakeGrafana serves the given json answers by path
+func fakeGrafana(t *testing.T, handler func(path string, query url.Values) any) *Runner {
+	t.Helper()
+	return fakeGrafanaRunner(fakeGrafanaURL(t, func(r *http.Request) any {
+		return handler(r.URL.Path, r.URL.Query())
+	}))
+}
+
+// fakeGrafanaRunner returns a runner with a client of the grafana at rawUrl
+func fakeGrafanaRunner(rawUrl string) *Runner {
+	u, _ := url.Parse(rawUrl)
+	return &Runner{client: goapi.NewHTTPClientWithConfig(strfmt.Default, &goapi.TransportConfig{Host: u.Host, BasePath: "/api", Schemes: []string{"http"}})}
+}
+
+// jsonModel decodes s into a new T
+func jsonModel[T any](t *testing.T, s string) *T {
+	t.Helper()
+	var res T
+	if err := json.Unmarshal([]byte(s), &res); err != nil {
+		t.Fatal(err)
+	}
+	return &res
+}
@@ -0,0 +1,167 @@
+package grafanasdkclistarter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana-foundation-sdk/go/dashboard"
+	"github.com/grafana/grafana-openapi-client-go/client/library_elements"
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/urfave/cli/v3"
+)
+
+// libraryPanelKind is the library element kind of panels
+const libraryPanelKind int64 = 1
+
+// volatileLibraryPanelFields are set by grafana inside the library panel model
+var volatileLibraryPanelFields = []string{"id", "libraryPanel", "gridPos"}
+
+// LibraryPanel is created or updated before the dashboards are applied.
+// Dashboards reference it by a panel with LibraryPanel(Ref()).
+type LibraryPanel struct {
+	Uid  string
+	Name string
+	// Folder is a path like Platform/Payments relative to the foldername flag
+	Folder string
+	Panel  dashboard.Panel
+}
+
+func (l LibraryPanel) Ref() dashboard.LibraryPanelRef {
+	return dashboard.LibraryPanelRef{Uid: l.Uid, Name: l.Name}
+}
+
+// getLibraryPanels returns the library panels with the full folder path
+func (r *Runner) getLibraryPanels(c *cli.Command) ([]LibraryPanel, error) {
+	if r.LibraryPanels == nil {
+		return nil, nil
+	}
+	foldername := c.String(CliFolderName)
+	panels, err := r.LibraryPanels(foldername, c)
+	if err != nil {
+		return nil, fmt.Errorf("failed get library panels %w", err)
+	}
+	for i, p := range panels {
+		if p.Uid == "" || p.Name == "" {
+			return nil, fmt.Errorf("library panel %q needs an uid and a name", p.Name)
+		}
+		panels[i].Folder = JoinFolderPath(foldername, p.Folder)
+	}
+	return panels, nil
+}
+
+// getRemoteLibraryPanel returns the library element with the given uid. If it does not exist found is false.
+func (r *Runner) getRemoteLibraryPanel(uid string) (*models.LibraryElementDTO, bool, error) {
+	res, err := r.client.LibraryElements.GetLibraryElementByUID(uid)
+	if err != nil {
+		var notFound *library_elements.GetLibraryElementByUIDNotFound
+		if errors.As(err, &notFound) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("unable to get library panel %s: %w", uid, err)
+	}
+	return res.Payload.Result, true, nil
+}
+
+func (r *Runner) applyLibraryPanels(panels []LibraryPanel) error {
+	for _, p := range panels {
+		folderUID, err := r.ensureFolderPath(p.Folder)
+		if err != nil {
+			return err
+		}
+		remote, found, err := r.getRemoteLibraryPanel(p.Uid)
+		if err != nil {
+			return err
+		}
+		if found {
+			_, err = r.client.LibraryElements.UpdateLibraryElement(p.Uid, &models.PatchLibraryElementCommand{
+				FolderUID: folderUID,
+				Kind:      libraryPanelKind,
+				Model:     p.Panel,
+				Name:      p.Name,
+				UID:       p.Uid,
+				Version:   remote.Version,
+			})
+		} else {
+			_, err = r.client.LibraryElements.CreateLibraryElement(&models.CreateLibraryElementCommand{
+				FolderUID: folderUID,
+				Kind:      libraryPanelKind,
+				Model:     p.Panel,
+				Name:      p.Name,
+				UID:       p.Uid,
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("unable to apply library panel %s: %w", p.Uid, err)
+		}
+		fmt.Printf("library panel %s: applied\n", p.Name)
+	}
+	return nil
+}
+
+func (r *Runner) planLibraryPanels(panels []LibraryPanel) ([]ResourceChange, error) {
+	var res []ResourceChange
+	for _, p := range panels {
+		change := ResourceChange{Kind: "library panel", Uid: p.Uid, Title: p.Name}
+		remote, found, err := r.getRemoteLibraryPanel(p.Uid)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			change.Action = PlanCreate
+			res = append(res, change)
+			continue
+		}
+		server, err := normalizeLibraryPanel(remote.Name, remote.FolderUID, remote.Model)
+		if err != nil {
+			return nil, err
+		}
+		local, err := normalizeLibraryPanel(p.Name, FolderUID(p.Folder), p.Panel)
+		if err != nil {
+			return nil, err
+		}
+		change.Diffs = diffValues("", server, local)
+		change.Action = PlanUnchanged
+		if len(change.Diffs) > 0 {
+			change.Action = PlanUpdate
+		}
+		res = append(res, change)
+	}
+	return res, nil
+}
+
+func normalizeLibraryPanel(name, folderUID string, model any) (map[string]any, error) {
+	var m map[string]any
+	if err := convertJSON(model, &m); err != nil {
+		return nil, err
+	}
+	for _, f := range volatileLibraryPanelFields {
+		delete(m, f)
+	}
+	return map[string]any{"name": name, "folderUid": folderUID, "model": m}, nil
+}
+
+// destroyLibraryPanels deletes all library panels which are not connected to any dashboard anymore
+func (r *Runner) destroyLibraryPanels(panels []LibraryPanel) error {
+	errList := errors.Join(nil)
+	for _, p := range panels {
+		connections, err := r.client.LibraryElements.GetLibraryElementConnections(p.Uid)
+		if err != nil {
+			var notFound *library_elements.GetLibraryElementConnectionsNotFound
+			if !errors.As(err, &notFound) {
+				errList = errors.Join(errList, fmt.Errorf("unable to get connections of library panel %s: %w", p.Uid, err))
+			}
+			continue
+		}
+		if n := len(connections.Payload.Result); n > 0 {
+			fmt.Printf("library panel %s: still connected to %d dashboard(s), keep it\n", p.Name, n)
+			continue
+		}
+		_, err = r.client.LibraryElements.DeleteLibraryElementByUID(p.Uid)
+		if err != nil {
+			errList = errors.Join(errList, fmt.Errorf("unable to delete library panel %s: %w", p.Uid, err))
+			continue
+		}
+		fmt.Printf("library panel %s: deleted\n", p.Name)
+	}
+	return errList
+}
@@ -0,0 +1,71 @@
+package grafanasdkclistarter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/grafana/grafana-foundation-sdk/go/timeseries"
+)
+
+func TestNormalizeLibraryPanel(t *testing.T) {
+	panel, err := timeseries.NewPanelBuilder().Title("Up").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := LibraryPanel{Uid: "up", Name: "Up", Folder: "Platform", Panel: panel}
+	tests := []struct {
+		name      string
+		folderUID string
+		edit      func(model map[string]any)
+		want      []string
+	}{
+		{
+			name:      "grafana managed fields",
+			folderUID: FolderUID("Platform"),
+			edit: func(model map[string]any) {
+				model["id"] = 12
+				model["gridPos"] = map[string]any{"h": 8, "w": 12, "x": 0, "y": 0}
+				model["libraryPanel"] = map[string]any{"uid": "up", "name": "Up"}
+			},
+		},
+		{
+			name:      "edited title",
+			folderUID: FolderUID("Platform"),
+			edit: func(model map[string]any) {
+				model["id"] = 12
+				model["title"] = "Down"
+			},
+			want: []string{`~ model.title: "Down" => "Up"`},
+		},
+		{
+			name:      "moved folder",
+			folderUID: FolderUID("Other"),
+			edit:      func(map[string]any) {},
+			want:      []string{`~ folderUid: "` + FolderUID("Other") + `" => "` + FolderUID("Platform") + `"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var model map[string]any
+			if err := convertJSON(local.Panel, &model); err != nil {
+				t.Fatal(err)
+			}
+			tt.edit(model)
+			server, err := normalizeLibraryPanel(local.Name, tt.folderUID, model)
+			if err != nil {
+				t.Fatal(err)
+			}
+			code, err := normalizeLibraryPanel(local.Name, FolderUID(local.Folder), local.Panel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, d := range diffValues("", server, code) {
+				got = append(got, d.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diff = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana-foundation-sdk/go/cog"
+	"github.com/grafana/grafana-openapi-client-go/client/provisioning"
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/urfave/cli/v3"
+)
+
+// redactedSecureValue is returned by grafana for secure contact point settings
+const redactedSecureValue = "[REDACTED]"
+
+// volatileNotificationFields are set by grafana and never part of the code
+var volatileNotificationFields = []string{"provenance", "version"}
+
+// notificationResources contains everything of the notification command converted to provisioning api models
+type notificationResources struct {
+	templates     []*models.NotificationTemplate
+	contactPoints []*models.EmbeddedContactPoint
+	muteTimings   []*models.MuteTimeInterval
+	policy        *models.Route
+}
+
+// provenanceHeader returns the X-Disable-Provenance header value. Without the header the
+// provisioned objects are locked against edits at the grafana ui.
+func provenanceHeader(c *cli.Command) *string {
+	if c.Bool(CliDisableProvenance) {
+		return cog.ToPtr("true")
+	}
+	return nil
+}
+
+func (r *Runner) NotificationApply(ctx context.Context, c *cli.Command) error {
+	res, err := r.getNotificationResources(c)
+	if err != nil {
+		return fmt.Errorf("failed apply notifications: %w", err)
+	}
+	provenance := provenanceHeader(c)
+
+	for _, t := range res.templates {
+		_, err := r.client.Provisioning.PutTemplate(provisioning.NewPutTemplateParamsWithContext(ctx).
+			WithName(t.Name).
+			WithBody(&models.NotificationTemplateContent{Template: t.Template}).
+			WithXDisableProvenance(provenance))
+		if err != nil {
+			return fmt.Errorf("unable to put notification template %s: %w", t.Name, err)
+		}
+		fmt.Printf("notification template %s: applied\n", t.Name)
+	}
+
+	if len(res.contactPoints) > 0 {
+		existing, err := r.client.Provisioning.GetContactpoints(provisioning.NewGetContactpointsParamsWithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("unable to get contact points: %w", err)
+		}
+		known := make(map[string]bool, len(existing.Payload))
+		for _, cp := range existing.Payload {
+			known[cp.UID] = true
+		}
+		for _, cp := range res.contactPoints {
+			if known[cp.UID] {
+				_, err = r.client.Provisioning.PutContactpoint(provisioning.NewPutContactpointParamsWithContext(ctx).
+					WithUID(cp.UID).
+					WithBody(cp).
+					WithXDisableProvenance(provenance))
+			} else {
+				_, err = r.client.Provisioning.PostContactpoints(provisioning.NewPostContactpointsParamsWithContext(ctx).
+					WithBody(cp).
+					WithXDisableProvenance(provenance))
+			}
+			if err != nil {
+				return fmt.Errorf("unable to apply contact point %s: %w", cp.Name, err)
+			}
+			fmt.Printf("contact point %s: applied\n", cp.Name)
+		}
+	}
+
+	if len(res.muteTimings) > 0 {
+		existing, err := r.client.Provisioning.GetMuteTimingsWithParams(provisioning.NewGetMuteTimingsParamsWithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("unable to get mute timings: %w", err)
+		}
+		known := make(map[string]bool, len(existing.Payload))
+		for _, m := range existing.Payload {
+			known[m.Name] = true
+		}
+		for _, m := range res.muteTimings {
+			if known[m.Name] {
+				_, err = r.client.Provisioning.PutMuteTiming(provisioning.NewPutMuteTimingParamsWithContext(ctx).
+					WithName(m.Name).
+					WithBody(m).
+					WithXDisableProvenance(provenance))
+			} else {
+				_, err = r.client.Provisioning.PostMuteTiming(provisioning.NewPostMuteTimingParamsWithContext(ctx).
+					WithBody(m).
+					WithXDisableProvenance(provenance))
+			}
+			if err != nil {
+				return fmt.Errorf("unable to apply mute timing %s: %w", m.Name, err)
+			}
+			fmt.Printf("mute timing %s: applied\n", m.Name)
+		}
+	}
+
+	if res.policy != nil {
+		_, err := r.client.Provisioning.PutPolicyTree(provisioning.NewPutPolicyTreeParamsWithContext(ctx).
+			WithBody(res.policy).
+			WithXDisableProvenance(provenance))
+		if err != nil {
+			return fmt.Errorf("unable to put notification policy tree: %w", err)
+		}
+		fmt.Println("notification policy tree: applied")
+	}
+
+	fmt.Println("Notifications applied")
+	return nil
+}
+
+func (r *Runner) NotificationPlan(ctx context.Context, c *cli.Command) error {
+	detailed := c.Bool(CliDetailedExitCode)
+	res, err := r.getNotificationResources(c)
+	if err != nil {
+		return planError(detailed, fmt.Errorf("failed plan notifications: %w", err))
+	}
+	var result PlanResult
+
+	if len(res.templates) > 0 {
+		existing, err := r.client.Provisioning.GetTemplatesWithParams(provisioning.NewGetTemplatesParamsWithContext(ctx))
+		if err != nil {
+			return planError(detailed, fmt.Errorf("unable to get notification templates: %w", err))
+		}
+		remote := make(map[string]any, len(existing.Payload))
+		for _, t := range existing.Payload {
+			remote[t.Name] = t
+		}
+		for _, t := range res.templates {
+			change, err := planNotificationResource("notification template", t.Name, t.Name, remote[t.Name], t)
+			if err != nil {
+				return planError(detailed, err)
+			}
+			result.Changes = append(result.Changes, change)
+		}
+	}
+
+	if len(res.contactPoints) > 0 {
+		existing, err := r.client.Provisioning.GetContactpoints(provisioning.NewGetContactpointsParamsWithContext(ctx))
+		if err != nil {
+			return planError(detailed, fmt.Errorf("unable to get contact points: %w", err))
+		}
+		remote := make(map[string]any, len(existing.Payload))
+		for _, cp := range existing.Payload {
+			remote[cp.UID] = cp
+		}
+		for _, cp := range res.contactPoints {
+			change, err := planNotificationResource("contact point", cp.UID, cp.Name, remote[cp.UID], cp)
+			if err != nil {
+				return planError(detailed, err)
+			}
+			result.Changes = append(result.Changes, change)
+		}
+	}
+
+	if len(res.muteTimings) > 0 {
+		existing, err := r.client.Provisioning.GetMuteTimingsWithParams(provisioning.NewGetMuteTimingsParamsWithContext(ctx))
+		if err != nil {
+			return planError(detailed, fmt.Errorf("unable to get mute timings: %w", err))
+		}
+		remote := make(map[string]any, len(existing.Payload))
+		for _, m := range existing.Payload {
+			remote[m.Name] = m
+		}
+		for _, m := range res.muteTimings {
+			change, err := planNotificationResource("mute timing", m.Name, m.Name, remote[m.Name], m)
+			if err != nil {
+				return planError(detailed, err)
+			}
+			result.Changes = append(result.Changes, change)
+		}
+	}
+
+	if res.policy != nil {
+		existing, err := r.client.Provisioning.GetPolicyTreeWithParams(provisioning.NewGetPolicyTreeParamsWithContext(ctx))
+		if err != nil {
+			return planError(detailed, fmt.Errorf("unable to get notification policy tree: %w", err))
+		}
+		change, err := planNotificationResource("notification policy", "root", "policy tree", existing.Payload, res.policy)
+		if err != nil {
+			return planError(detailed, err)
+		}
+		result.Changes = append(result.Changes, change)
+	}
+
+	return finishPlan(detailed, result)
+}
+
+func (r *Runner) NotificationDestroy(ctx context.Context, c *cli.Command) error {
+	res, err := r.getNotificationResources(c)
+	if err != nil {
+		return fmt.Errorf("failed destroy notifications: %w", err)
+	}
+	provenance := provenanceHeader(c)
+	errList := errors.Join(nil)
+
+	if res.policy != nil {
+		_, err := r.client.Provisioning.ResetPolicyTreeWithParams(provisioning.NewResetPolicyTreeParamsWithContext(ctx))
+		if err != nil {
+			errList = errors.Join(errList, fmt.Errorf("unable to reset notification policy tree: %w", err))
+		}
+	}
+	for _, m := range res.muteTimings {
+		_, err := r.client.Provisioning.DeleteMuteTiming(provisioning.NewDeleteMuteTimingParamsWithContext(ctx).
+			WithName(m.Name).
+			WithXDisableProvenance(provenance))
+		if err != nil {
+			errList = errors.Join(errList, fmt.Errorf("unable to delete mute timing %s: %w", m.Name, err))
+		}
+	}
+	for _, cp := range res.contactPoints {
+		_, err := r.client.Provisioning.DeleteContactpoints(cp.UID)
+		if err != nil {
+			errList = errors.Join(errList, fmt.Errorf("unable to delete contact point %s: %w", cp.Name, err))
+		}
+	}
+	for _, t := range res.templates {
+		_, err := r.client.Provisioning.DeleteTemplate(provisioning.NewDeleteTemplateParamsWithContext(ctx).WithName(t.Name))
+		if err != nil {
+			errList = errors.Join(errList, fmt.Errorf("unable to delete notification template %s: %w", t.Name, err))
+		}
+	}
+
+	if errList != nil {
+		return errList
+	}
+	fmt.Println("Destroyed")
+	return nil
+}
+
+// getNotificationResources calls all configured creators and converts the result to provisioning api models
+func (r *Runner) getNotificationResources(c *cli.Command) (notificationResources, error) {
+	var res notificationResources
+	if r.ContactPoints == nil && r.NotificationPolicy == nil && r.MuteTimings == nil && r.NotificationTemplates == nil {
+		return res, fmt.Errorf("no notification creator set, use ContactPointBuilder, NotificationPolicyBuilder, MuteTimingBuilder or NotificationTemplateBuilder option")
+	}
+
+	if r.NotificationTemplates != nil {
+		templates, err := r.NotificationTemplates(c)
+		if err != nil {
+			return res, fmt.Errorf("failed get notification templates %w", err)
+		}
+		for _, t := range templates {
+			if t.Name == nil {
+				return res, fmt.Errorf("notification template without name")
+			}
+			var m models.NotificationTemplate
+			if err := convertJSON(t, &m); err != nil {
+				return res, err
+			}
+			res.templates = append(res.templates, &m)
+		}
+	}
+
+	if r.ContactPoints != nil {
+		contactPoints, err := r.ContactPoints(c)
+		if err != nil {
+			return res, fmt.Errorf("failed get contact points %w", err)
+		}
+		for _, cp := range contactPoints {
+			if cp.Uid == nil || *cp.Uid == "" {
+				return res, fmt.Errorf("contact point %s needs an uid", cog.Unptr(cp.Name))
+			}
+			var m models.EmbeddedContactPoint
+			if err := convertJSON(cp, &m); err != nil {
+				return res, err
+			}
+			res.contactPoints = append(res.contactPoints, &m)
+		}
+	}
+
+	if r.MuteTimings != nil {
+		muteTimings, err := r.MuteTimings(c)
+		if err != nil {
+			return res, fmt.Errorf("failed get mute timings %w", err)
+		}
+		for _, mt := range muteTimings {
+			if mt.Name == nil {
+				return res, fmt.Errorf("mute timing without name")
+			}
+			var m models.MuteTimeInterval
+			if err := convertJSON(mt, &m); err != nil {
+				return res, err
+			}
+			res.muteTimings = append(res.muteTimings, &m)
+		}
+	}
+
+	if r.NotificationPolicy != nil {
+		policy, err := r.NotificationPolicy(c)
+		if err != nil {
+			return res, fmt.Errorf("failed get notification policy %w", err)
+		}
+		var m models.Route
+		if err := convertJSON(policy, &m); err != nil {
+			return res, err
+		}
+		res.policy = &m
+	}
+	return res, nil
+}
+
+// planNotificationResource compares the remote model (nil if it does not exist) with the local model
+func planNotificationResource(kind, uid, title string, remote, local any) (ResourceChange, error) {
+	change := ResourceChange{Kind: kind, Uid: uid, Title: title}
+	if remote == nil {
+		change.Action = PlanCreate
+		return change, nil
+	}
+	server, err := normalizeNotificationResource(remote)
+	if err != nil {
+		return change, err
+	}
+	code, err := normalizeNotificationResource(local)
+	if err != nil {
+		return change, err
+	}
+	// secure settings are never returned by grafana
+	if settings, ok := server["settings"].(map[string]any); ok {
+		for k, v := range settings {
+			if v == redactedSecureValue {
+				delete(settings, k)
+				if s, ok := code["settings"].(map[string]any); ok {
+					delete(s, k)
+				}
+			}
+		}
+	}
+	change.Diffs = diffValues("", server, code)
+	change.Action = PlanUnchanged
+	if len(change.Diffs) > 0 {
+		change.Action = PlanUpdate
+	}
+	return change, nil
+}
+
+func normalizeNotificationResource(v any) (map[string]any, error) {
+	var res map[string]any
+	if err := convertJSON(v, &res); err != nil {
+		return nil, err
+	}
+	removeFields(res, volatileNotificationFields)
+	return res, nil
+}
+
+// removeFields deletes the given keys recursively (needed for nested notification policies)
+func removeFields(v any, fields []string) {
+	switch t := v.(type) {
+	case map[string]any:
+		for _, f := range fields {
+			delete(t, f)
+		}
+		for _, e := range t {
+			removeFields(e, fields)
+		}
+	case []any:
+		for _, e := range t {
+			removeFields(e, fields)
+		}
+	}
+}
+
+// convertJSON converts in to out by marshal and unmarshal it
+func convertJSON(in, out any) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("unable to marshal %T: %w", in, err)
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("unable to unmarshal %T: %w", out, err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package grafanasdkclistarter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/grafana/grafana-openapi-client-go/models"
+)
+
+func TestPlanNotificationResource(t *testing.T) {
+	tests := []struct {
+		name       string
+		remote     any
+		local      any
+		wantAction PlanAction
+		want       []string
+	}{
+		{
+			name:       "not existing",
+			local:      jsonModel[models.EmbeddedContactPoint](t, `{"uid":"cp","name":"team","type":"webhook","settings":{"url":"http://hook"}}`),
+			wantAction: PlanCreate,
+		},
+		{
+			name:       "provenance and redacted secrets of a contact point",
+			remote:     jsonModel[models.EmbeddedContactPoint](t, `{"uid":"cp","name":"team","type":"webhook","provenance":"api","settings":{"url":"http://hook","password":"[REDACTED]"}}`),
+			local:      jsonModel[models.EmbeddedContactPoint](t, `{"uid":"cp","name":"team","type":"webhook","settings":{"url":"http://hook","password":"secret"}}`),
+			wantAction: PlanUnchanged,
+		},
+		{
+			name:       "edited contact point",
+			remote:     jsonModel[models.EmbeddedContactPoint](t, `{"uid":"cp","name":"team","type":"webhook","provenance":"api","settings":{"url":"http://hook","password":"[REDACTED]"}}`),
+			local:      jsonModel[models.EmbeddedContactPoint](t, `{"uid":"cp","name":"team","type":"webhook","settings":{"url":"http://other","password":"secret"}}`),
+			wantAction: PlanUpdate,
+			want:       []string{`~ settings.url: "http://hook" => "http://other"`},
+		},
+		{
+			name:       "version of a mute timing",
+			remote:     jsonModel[models.MuteTimeInterval](t, `{"name":"night","version":"a1b2","provenance":"api","time_intervals":[{"weekdays":["saturday"]}]}`),
+			local:      jsonModel[models.MuteTimeInterval](t, `{"name":"night","time_intervals":[{"weekdays":["saturday"]}]}`),
+			wantAction: PlanUnchanged,
+		},
+		{
+			name:       "nested provenance of the policy tree",
+			remote:     jsonModel[models.Route](t, `{"receiver":"team","provenance":"api","routes":[{"receiver":"oncall","provenance":"api"}]}`),
+			local:      jsonModel[models.Route](t, `{"receiver":"team","routes":[{"receiver":"oncall"}]}`),
+			wantAction: PlanUnchanged,
+		},
+		{
+			name:       "edited policy tree",
+			remote:     jsonModel[models.Route](t, `{"receiver":"team","provenance":"api","routes":[{"receiver":"oncall","provenance":"api"}]}`),
+			local:      jsonModel[models.Route](t, `{"receiver":"team","routes":[{"receiver":"night"}]}`),
+			wantAction: PlanUpdate,
+			want:       []string{`~ routes[0].receiver: "oncall" => "night"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change, err := planNotificationResource("test", "uid", "title", tt.remote, tt.local)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if change.Action != tt.wantAction {
+				t.Errorf("action = %s, want %s", change.Action, tt.wantAction)
+			}
+			var got []string
+			for _, d := range change.Diffs {
+				got = append(got, d.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diff = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
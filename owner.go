@@ -0,0 +1,132 @@
+package grafanasdkclistarter
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	grafanaDashboards "github.com/grafana/grafana-openapi-client-go/client/dashboards"
+	"github.com/grafana/grafana-openapi-client-go/models"
+)
+
+const (
+	// ManagedByTagPrefix is the dashboard tag prefix which marks the app owning the dashboard
+	ManagedByTagPrefix = "managed-by:"
+	// OwnerMetadataKey is the dashboard json key where the OwnerMetadata is stored
+	OwnerMetadataKey = "grafanaSdkCliStarter"
+)
+
+type OwnerMetadata struct {
+	App       string    `json:"app"`
+	Commit    string    `json:"commit"`
+	AppliedAt time.Time `json:"appliedAt"`
+}
+
+func ManagedByTag(appName string) string {
+	return ManagedByTagPrefix + appName
+}
+
+// stampOwner adds the managed-by tag and the OwnerMetadata to a normalized dashboard
+func stampOwner(d map[string]any, meta OwnerMetadata) {
+	addOwnerTag(d, meta.App)
+	d[OwnerMetadataKey] = meta
+}
+
+func addOwnerTag(d map[string]any, appName string) {
+	tag := ManagedByTag(appName)
+	tags, _ := d["tags"].([]any)
+	for _, t := range tags {
+		if t == tag {
+			return
+		}
+	}
+	d["tags"] = append(tags, tag)
+}
+
+// dashboardOwner returns the app owning the normalized dashboard or an empty string if it is not managed by any app
+func dashboardOwner(d map[string]any) string {
+	tags, _ := d["tags"].([]any)
+	for _, t := range tags {
+		if s, ok := t.(string); ok && strings.HasPrefix(s, ManagedByTagPrefix) {
+			return strings.TrimPrefix(s, ManagedByTagPrefix)
+		}
+	}
+	return ""
+}
+
+func hitOwnedBy(h *models.Hit, appName string) bool {
+	return slices.Contains(h.Tags, ManagedByTag(appName))
+}
+
+// getRemoteDashboard returns the normalized dashboard with the given uid from the server.
+// If the dashboard does not exist found is false.
+func (r *Runner) getRemoteDashboard(uid string) (d map[string]any, meta *models.DashboardMeta, found bool, err error) {
+	remote, err := r.client.Dashboards.GetDashboardByUID(uid)
+	if err != nil {
+		var notFound *grafanaDashboards.GetDashboardByUIDNotFound
+		if errors.As(err, &notFound) {
+			return nil, nil, false, nil
+		}
+		return nil, nil, false, fmt.Errorf("unable to get dashboard %s: %w", uid, err)
+	}
+	d, err = normalizeDashboard(remote.Payload.Dashboard)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return d, remote.Payload.Meta, true, nil
+}
+
+// checkOwner returns an error if the dashboard is owned by another app.
+// Dashboards not managed by any app are only taken over with adopt.
+func (r *Runner) checkOwner(uid string, d map[string]any, adopt bool) error {
+	owner := dashboardOwner(d)
+	if owner == "" && !adopt {
+		return fmt.Errorf("dashboard %s is not managed by any app, use --%s to take it over", uid, CliAdopt)
+	}
+	if owner != "" && owner != r.appName {
+		return fmt.Errorf("dashboard %s is managed by %s and not by %s", uid, owner, r.appName)
+	}
+	return nil
+}
+
+// checkDashboardOwners checks all existing dashboards before anything is written
+func (r *Runner) checkDashboardOwners(dashboards []FolderDashboard, adopt bool) error {
+	errList := errors.Join(nil)
+	for _, fd := range dashboards {
+		d := fd.Dashboard
+		if d.Uid == nil {
+			return fmt.Errorf("dashboard %s has no uid", dashboardTitle(d))
+		}
+		remote, _, found, err := r.getRemoteDashboard(*d.Uid)
+		if err != nil {
+			return err
+		}
+		if found {
+			errList = errors.Join(errList, r.checkOwner(*d.Uid, remote, adopt))
+		}
+	}
+	return errList
+}
+
+// currentCommit tries to find the git commit the dashboards are build from
+func currentCommit(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" && s.Value != "" {
+				return s.Value
+			}
+		}
+	}
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err == nil {
+		return strings.TrimSpace(string(out))
+	}
+	return "unknown"
+}
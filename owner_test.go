@@ -0,0 +1,209 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-foundation-sdk/go/dashboard"
+	"github.com/urfave/cli/v3"
+)
+
+// dashboardStore is a fake grafana keeping dashboards by uid
+type dashboardStore struct {
+	mu         sync.Mutex
+	dashboards map[string]map[string]any
+	posted     []string
+	deleted    []string
+}
+
+func newDashboardStore(t *testing.T, dashboards ...map[string]any) (*dashboardStore, string) {
+	t.Helper()
+	s := &dashboardStore{dashboards: map[string]map[string]any{}}
+	for _, d := range dashboards {
+		s.dashboards[d["uid"].(string)] = d
+	}
+	return s, fakeGrafanaURL(t, s.serve)
+}
+
+func (s *dashboardStore) serve(r *http.Request) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	uid, byUID := strings.CutPrefix(r.URL.Path, "/api/dashboards/uid/")
+	switch {
+	case r.Method == http.MethodGet && byUID:
+		if d, ok := s.dashboards[uid]; ok {
+			return map[string]any{"dashboard": d, "meta": map[string]any{"folderUid": ""}}
+		}
+	case r.Method == http.MethodDelete && byUID:
+		if _, ok := s.dashboards[uid]; ok {
+			delete(s.dashboards, uid)
+			s.deleted = append(s.deleted, uid)
+			return map[string]any{"title": uid, "message": "deleted", "id": 1}
+		}
+	case r.Method == http.MethodPost && r.URL.Path == "/api/dashboards/db":
+		var cmd struct {
+			Dashboard map[string]any `json:"dashboard"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&cmd); err != nil {
+			return nil
+		}
+		uid := cmd.Dashboard["uid"].(string)
+		s.dashboards[uid] = cmd.Dashboard
+		s.posted = append(s.posted, uid)
+		return map[string]any{"uid": uid, "url": "/d/" + uid, "status": "success", "version": 1, "id": 1}
+	}
+	return nil
+}
+
+// ownedDashboard returns the normalized dashboard as grafana keeps it, with the owner tag of app if set
+func ownedDashboard(t *testing.T, uid, app string) map[string]any {
+	t.Helper()
+	d, err := dashboard.NewDashboardBuilder(uid).Uid(uid).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := normalizeDashboard(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app != "" {
+		addOwnerTag(body, app)
+	}
+	return body
+}
+
+// ownerTestCli returns the cli of app "test" with a dashboard in code for every uid
+func ownerTestCli(t *testing.T, uids ...string) *cli.Command {
+	t.Helper()
+	app, err := NewCli("test", DashboardBuilder(func(folderName string, c *cli.Command) ([]dashboard.Dashboard, error) {
+		var res []dashboard.Dashboard
+		for _, uid := range uids {
+			d, err := dashboard.NewDashboardBuilder(uid).Uid(uid).Build()
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, d)
+		}
+		return res, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func TestCheckOwner(t *testing.T) {
+	r := &Runner{appName: "test"}
+	tests := []struct {
+		name    string
+		owner   string
+		adopt   bool
+		wantErr string
+	}{
+		{name: "own", owner: "test"},
+		{name: "own with adopt", owner: "test", adopt: true},
+		{name: "foreign", owner: "other", wantErr: "managed by other"},
+		{name: "foreign with adopt", owner: "other", adopt: true, wantErr: "managed by other"},
+		{name: "unowned", wantErr: "--adopt"},
+		{name: "unowned with adopt", adopt: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.checkOwner("api", ownedDashboard(t, "api", tt.owner), tt.adopt)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("checkOwner() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("checkOwner() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyChecksAllOwnersBeforeWriting(t *testing.T) {
+	tests := []struct {
+		name       string
+		remote     []map[string]any
+		args       []string
+		wantErr    bool
+		wantPosted []string
+	}{
+		{name: "new and own", remote: []map[string]any{ownedDashboard(t, "b", "test")}, wantPosted: []string{"a", "b"}},
+		{name: "foreign owner", remote: []map[string]any{ownedDashboard(t, "b", "other")}, wantErr: true},
+		{name: "unowned without adopt", remote: []map[string]any{ownedDashboard(t, "b", "")}, wantErr: true},
+		{name: "unowned with adopt", remote: []map[string]any{ownedDashboard(t, "b", "")}, args: []string{"--adopt"}, wantPosted: []string{"a", "b"}},
+		{name: "foreign owner with adopt", remote: []map[string]any{ownedDashboard(t, "b", "other")}, args: []string{"--adopt"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, server := newDashboardStore(t, tt.remote...)
+			err := ownerTestCli(t, "a", "b").Run(context.Background(), append([]string{"test", "dashboard", "apply", "--server", server, "--apikey", "key"}, tt.args...))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("apply error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(store.posted, tt.wantPosted) {
+				t.Errorf("posted dashboards = %q, want %q", store.posted, tt.wantPosted)
+			}
+			for _, uid := range tt.wantPosted {
+				if owner := dashboardOwner(store.dashboards[uid]); owner != "test" {
+					t.Errorf("dashboard %s is owned by %q after apply, want test", uid, owner)
+				}
+			}
+		})
+	}
+}
+
+func TestDestroyRefusesForeignDashboards(t *testing.T) {
+	store, server := newDashboardStore(t, ownedDashboard(t, "own", "test"), ownedDashboard(t, "foreign", "other"), ownedDashboard(t, "unowned", ""))
+	err := ownerTestCli(t, "own", "foreign", "unowned", "missing").Run(context.Background(), []string{"test", "dashboard", "destroy", "--server", server, "--apikey", "key"})
+	if err == nil || !strings.Contains(err.Error(), "refuse to delete dashboard foreign") || !strings.Contains(err.Error(), "refuse to delete dashboard unowned") {
+		t.Errorf("destroy error = %v, want refused foreign and unowned dashboards", err)
+	}
+	if want := []string{"own"}; !reflect.DeepEqual(store.deleted, want) {
+		t.Errorf("deleted dashboards = %q, want %q", store.deleted, want)
+	}
+}
+
+func TestStampOwnerRoundTrip(t *testing.T) {
+	store, server := newDashboardStore(t)
+	app := ownerTestCli(t, "api")
+	if err := app.Run(context.Background(), []string{"test", "dashboard", "apply", "--server", server, "--apikey", "key", "--commit", "abc123"}); err != nil {
+		t.Fatal(err)
+	}
+	r := fakeGrafanaRunner(server)
+	r.appName = "test"
+	remote, _, found, err := r.getRemoteDashboard("api")
+	if err != nil || !found {
+		t.Fatalf("applied dashboard not found: %v", err)
+	}
+	if owner := dashboardOwner(remote); owner != "test" {
+		t.Errorf("owner after round trip = %q, want test", owner)
+	}
+	b, err := json.Marshal(remote[OwnerMetadataKey])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta OwnerMetadata
+	if err := json.Unmarshal(b, &meta); err != nil {
+		t.Fatalf("%s is no owner metadata: %s", OwnerMetadataKey, err)
+	}
+	if meta.App != "test" || meta.Commit != "abc123" || time.Since(meta.AppliedAt) > time.Minute {
+		t.Errorf("owner metadata = %+v, want app test and commit abc123", meta)
+	}
+	if err := r.checkOwner("api", remote, false); err != nil {
+		t.Errorf("applied dashboard is not owned: %s", err)
+	}
+	// a second plan sees no changes, the stamp is no diff
+	if got := exitCode(t, ownerTestCli(t, "api"), "dashboard", "plan", "--detailed-exitcode", "--server", server, "--apikey", "key"); got != PlanExitCodeNoChanges {
+		t.Errorf("plan after apply exit code = %d, want %d", got, PlanExitCodeNoChanges)
+	}
+	if len(store.posted) != 1 {
+		t.Errorf("posted %d dashboards, want 1", len(store.posted))
+	}
+}
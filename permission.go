@@ -0,0 +1,300 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/grafana/grafana-foundation-sdk/go/cog"
+	"github.com/grafana/grafana-openapi-client-go/client/dashboard_permissions"
+	"github.com/grafana/grafana-openapi-client-go/client/folder_permissions"
+	"github.com/grafana/grafana-openapi-client-go/client/teams"
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/urfave/cli/v3"
+)
+
+type PermissionLevel = models.PermissionType
+
+const (
+	PermissionView  PermissionLevel = 1
+	PermissionEdit  PermissionLevel = 2
+	PermissionAdmin PermissionLevel = 4
+)
+
+var permissionNames = map[PermissionLevel]string{
+	PermissionView:  "View",
+	PermissionEdit:  "Edit",
+	PermissionAdmin: "Admin",
+}
+
+// Permission grants a role (Viewer, Editor, Admin), a team (by name) or a user (by login or email) access.
+// Exactly one of Role, Team and User has to be set.
+type Permission struct {
+	Role       string
+	Team       string
+	User       string
+	Permission PermissionLevel
+}
+
+func (p Permission) key() (string, error) {
+	switch {
+	case p.Role != "" && p.Team == "" && p.User == "":
+		return "role:" + p.Role, nil
+	case p.Team != "" && p.Role == "" && p.User == "":
+		return "team:" + p.Team, nil
+	case p.User != "" && p.Role == "" && p.Team == "":
+		return "user:" + p.User, nil
+	}
+	return "", fmt.Errorf("permission needs exactly one of role, team or user: %+v", p)
+}
+
+// Permissions replaces all not inherited permissions of the given folders and dashboards
+type Permissions struct {
+	// Folders maps a folder path relative to the foldername flag ("" for the folder itself) to its permissions
+	Folders map[string][]Permission
+	// Dashboards maps a dashboard uid to its permissions
+	Dashboards map[string][]Permission
+}
+
+const (
+	folderPermissionsKind    = "folder permissions"
+	dashboardPermissionsKind = "dashboard permissions"
+)
+
+// permissionTarget is a folder or dashboard whose permissions are reconciled
+type permissionTarget struct {
+	kind        string
+	uid         string
+	title       string
+	permissions []Permission
+}
+
+func (r *Runner) getPermissionTargets(c *cli.Command) ([]permissionTarget, error) {
+	if r.Permissions == nil {
+		return nil, nil
+	}
+	foldername := c.String(CliFolderName)
+	p, err := r.Permissions(foldername, c)
+	if err != nil {
+		return nil, fmt.Errorf("failed get permissions %w", err)
+	}
+	var res []permissionTarget
+	for _, path := range sortedKeys(p.Folders) {
+		full := JoinFolderPath(foldername, path)
+		if full == "" {
+			return nil, fmt.Errorf("permissions of the general folder can not be managed")
+		}
+		res = append(res, permissionTarget{kind: folderPermissionsKind, uid: FolderUID(full), title: full, permissions: p.Folders[path]})
+	}
+	for _, uid := range sortedKeys(p.Dashboards) {
+		res = append(res, permissionTarget{kind: dashboardPermissionsKind, uid: uid, title: uid, permissions: p.Dashboards[uid]})
+	}
+	return res, nil
+}
+
+// currentPermissions returns the not inherited permissions by key. If the folder or dashboard does not exist found is false.
+func (r *Runner) currentPermissions(t permissionTarget) (res map[string]any, found bool, err error) {
+	var items []*models.DashboardACLInfoDTO
+	if t.kind == folderPermissionsKind {
+		p, err := r.client.FolderPermissions.GetFolderPermissionList(t.uid)
+		if err != nil {
+			var notFound *folder_permissions.GetFolderPermissionListNotFound
+			if errors.As(err, &notFound) {
+				return nil, false, nil
+			}
+			return nil, false, fmt.Errorf("unable to get permissions of folder %s: %w", t.title, err)
+		}
+		items = p.Payload
+	} else {
+		p, err := r.client.DashboardPermissions.GetDashboardPermissionsListByUID(t.uid)
+		if err != nil {
+			var notFound *dashboard_permissions.GetDashboardPermissionsListByUIDNotFound
+			if errors.As(err, &notFound) {
+				return nil, false, nil
+			}
+			return nil, false, fmt.Errorf("unable to get permissions of dashboard %s: %w", t.uid, err)
+		}
+		items = p.Payload
+	}
+	res = map[string]any{}
+	for _, i := range items {
+		if i.Inherited {
+			continue
+		}
+		var key string
+		switch {
+		case i.TeamID != 0:
+			key = "team:" + i.Team
+		case i.UserID != 0:
+			key = "user:" + i.UserLogin
+		case i.Role != "":
+			key = "role:" + i.Role
+		default:
+			continue
+		}
+		res[key] = permissionNames[i.Permission]
+	}
+	return res, true, nil
+}
+
+// desiredPermissions returns the permissions by key, users are keyed by their login like the current permissions
+func (r *Runner) desiredPermissions(t permissionTarget) (map[string]any, error) {
+	res := map[string]any{}
+	for _, p := range t.permissions {
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		if p.User != "" {
+			u, err := r.findUser(p.User)
+			if err != nil {
+				return nil, err
+			}
+			key = "user:" + u.Login
+		}
+		name, ok := permissionNames[p.Permission]
+		if !ok {
+			return nil, fmt.Errorf("unknown permission level %d for %s", p.Permission, key)
+		}
+		res[key] = name
+	}
+	return res, nil
+}
+
+func (r *Runner) planPermissions(targets []permissionTarget) ([]ResourceChange, error) {
+	var res []ResourceChange
+	for _, t := range targets {
+		change := ResourceChange{Kind: t.kind, Uid: t.uid, Title: t.title}
+		desired, err := r.desiredPermissions(t)
+		if err != nil {
+			return nil, err
+		}
+		current, found, err := r.currentPermissions(t)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			change.Action = PlanCreate
+			res = append(res, change)
+			continue
+		}
+		change.Diffs = diffValues("", current, desired)
+		change.Action = PlanUnchanged
+		if len(change.Diffs) > 0 {
+			change.Action = PlanUpdate
+		}
+		res = append(res, change)
+	}
+	return res, nil
+}
+
+// applyPermissions reconciles all permissions of the PermissionCreator. Missing folders are created, dashboards have to exist already.
+func (r *Runner) applyPermissions(ctx context.Context, c *cli.Command) error {
+	targets, err := r.getPermissionTargets(c)
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		if t.kind == folderPermissionsKind {
+			if _, err := r.ensureFolderPath(t.title); err != nil {
+				return err
+			}
+		}
+	}
+	changes, err := r.planPermissions(targets)
+	if err != nil {
+		return err
+	}
+	for i, t := range targets {
+		if changes[i].Action == PlanUnchanged {
+			continue
+		}
+		items := make([]*models.DashboardACLUpdateItem, 0, len(t.permissions))
+		for _, p := range t.permissions {
+			item, err := r.toACLItem(ctx, p)
+			if err != nil {
+				return err
+			}
+			items = append(items, item)
+		}
+		cmd := &models.UpdateDashboardACLCommand{Items: items}
+		if t.kind == folderPermissionsKind {
+			_, err = r.client.FolderPermissions.UpdateFolderPermissions(t.uid, cmd)
+		} else {
+			_, err = r.client.DashboardPermissions.UpdateDashboardPermissionsByUID(t.uid, cmd)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to update %s of %s: %w", t.kind, t.title, err)
+		}
+		fmt.Printf("%s %s: updated\n", t.kind, t.title)
+	}
+	return nil
+}
+
+func (r *Runner) toACLItem(ctx context.Context, p Permission) (*models.DashboardACLUpdateItem, error) {
+	item := &models.DashboardACLUpdateItem{Permission: p.Permission, Role: p.Role}
+	if p.Team != "" {
+		t, err := r.findTeam(ctx, p.Team)
+		if err != nil {
+			return nil, err
+		}
+		item.TeamID = t.ID
+	}
+	if p.User != "" {
+		u, err := r.findUser(p.User)
+		if err != nil {
+			return nil, err
+		}
+		item.UserID = u.ID
+	}
+	return item, nil
+}
+
+// findTeam returns the team with exactly this name, the team search of grafana matches parts of names too
+func (r *Runner) findTeam(ctx context.Context, name string) (*models.TeamDTO, error) {
+	t, err := r.client.Teams.SearchTeams(teams.NewSearchTeamsParamsWithContext(ctx).WithName(cog.ToPtr(name)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to find team %s: %w", name, err)
+	}
+	var res []*models.TeamDTO
+	if t.Payload != nil {
+		for _, team := range t.Payload.Teams {
+			if team.Name == name {
+				res = append(res, team)
+			}
+		}
+	}
+	switch len(res) {
+	case 0:
+		return nil, fmt.Errorf("team %s does not exist", name)
+	case 1:
+		return res[0], nil
+	}
+	return nil, fmt.Errorf("team name %s is ambiguous, %d teams found", name, len(res))
+}
+
+// findUser resolves a login or email of a Permission, users are cached
+func (r *Runner) findUser(user string) (*models.UserProfileDTO, error) {
+	if u, ok := r.knownUsers[user]; ok {
+		return u, nil
+	}
+	u, err := r.client.Users.GetUserByLoginOrEmail(user)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find user %s: %w", user, err)
+	}
+	if r.knownUsers == nil {
+		r.knownUsers = map[string]*models.UserProfileDTO{}
+	}
+	r.knownUsers[user] = u.Payload
+	return u.Payload, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	slices.Sort(res)
+	return res
+}
@@ -0,0 +1,71 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDesiredPermissionsKeyUsersByLogin(t *testing.T) {
+	r := fakeGrafana(t, func(path string, query url.Values) any {
+		if path == "/api/users/lookup" && query.Get("loginOrEmail") == "alice@example.com" {
+			return map[string]any{"id": 7, "login": "alice", "email": "alice@example.com"}
+		}
+		return nil
+	})
+	got, err := r.desiredPermissions(permissionTarget{permissions: []Permission{
+		{User: "alice@example.com", Permission: PermissionAdmin},
+		{Role: "Viewer", Permission: PermissionView},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"user:alice": "Admin", "role:Viewer": "View"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("desiredPermissions() = %v, want %v", got, want)
+	}
+}
+
+func TestFindTeam(t *testing.T) {
+	r := fakeGrafana(t, func(path string, query url.Values) any {
+		if path != "/api/teams/search" {
+			return nil
+		}
+		all := []map[string]any{{"id": 1, "name": "payments"}, {"id": 2, "name": "payments-oncall"}, {"id": 3, "name": "twice"}, {"id": 4, "name": "twice"}}
+		var teams []map[string]any
+		for _, team := range all {
+			if strings.Contains(team["name"].(string), query.Get("name")) {
+				teams = append(teams, team)
+			}
+		}
+		return map[string]any{"teams": teams, "totalCount": len(teams)}
+	})
+	tests := []struct {
+		name    string
+		want    int64
+		wantErr string
+	}{
+		{name: "payments", want: 1},
+		{name: "oncall", wantErr: "does not exist"},
+		{name: "twice", wantErr: "ambiguous"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.findTeam(context.Background(), tt.name)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("findTeam(%s) error = %v, want %s", tt.name, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != tt.want {
+				t.Errorf("findTeam(%s) = team %d, want %d", tt.name, got.ID, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana-foundation-sdk/go/dashboard"
+	"github.com/urfave/cli/v3"
+)
+
+type PlanAction string
+
+const (
+	PlanCreate    PlanAction = "create"
+	PlanUpdate    PlanAction = "update"
+	PlanUnchanged PlanAction = "unchanged"
+	// PlanConflict the dashboard exists but is owned by another app
+	PlanConflict PlanAction = "conflict"
+	// PlanAdopt the dashboard exists without owner and is taken over (--adopt)
+	PlanAdopt PlanAction = "adopt"
+)
+
+type ResourceChange struct {
+	Kind   string
+	Uid    string
+	Title  string
+	Action PlanAction
+	Diffs  []FieldDiff
+}
+
+type PlanResult struct {
+	Changes []ResourceChange
+}
+
+func (p PlanResult) Count(action PlanAction) int {
+	res := 0
+	for _, c := range p.Changes {
+		if c.Action == action {
+			res++
+		}
+	}
+	return res
+}
+
+func (p PlanResult) HasChanges() bool {
+	return p.Count(PlanCreate)+p.Count(PlanUpdate)+p.Count(PlanAdopt) > 0
+}
+
+// Plan exit codes if detailed-exitcode is set (like terraform plan -detailed-exitcode)
+const (
+	PlanExitCodeNoChanges = 0
+	PlanExitCodeError     = 1
+	PlanExitCodeChanges   = 2
+)
+
+func (r *Runner) Plan(ctx context.Context, c *cli.Command) error {
+	detailed := c.Bool(CliDetailedExitCode)
+	dashboards, err := r.getDashboards(ctx, c)
+	if err != nil {
+		return planError(detailed, fmt.Errorf("failed plan %w ", err))
+	}
+	panels, err := r.getLibraryPanels(c)
+	if err != nil {
+		return planError(detailed, fmt.Errorf("failed plan %w ", err))
+	}
+	libraryPanels, err := r.planLibraryPanels(panels)
+	if err != nil {
+		return planError(detailed, fmt.Errorf("failed plan %w ", err))
+	}
+	result, err := r.planDashboards(dashboards, c.Bool(CliAdopt))
+	if err != nil {
+		return planError(detailed, fmt.Errorf("failed plan %w ", err))
+	}
+	result.Changes = append(libraryPanels, result.Changes...)
+	targets, err := r.getPermissionTargets(c)
+	if err != nil {
+		return planError(detailed, fmt.Errorf("failed plan %w ", err))
+	}
+	permissions, err := r.planPermissions(targets)
+	if err != nil {
+		return planError(detailed, fmt.Errorf("failed plan %w ", err))
+	}
+	result.Changes = append(result.Changes, permissions...)
+	return finishPlan(detailed, result)
+}
+
+// finishPlan prints the result and returns the error matching the exit code contract
+func finishPlan(detailed bool, result PlanResult) error {
+	printPlan(result)
+	if n := result.Count(PlanConflict); n > 0 {
+		return planError(detailed, fmt.Errorf("failed plan: %d resource(s) not managed by this app", n))
+	}
+	if detailed && result.HasChanges() {
+		return cli.Exit("", PlanExitCodeChanges)
+	}
+	return nil
+}
+
+// PlanBefore connects like Before. Its errors and missing required flags exit with the error exit code,
+// urfave/cli checks required flags only after Before and returns them without exit code.
+func (r *Runner) PlanBefore(ctx context.Context, c *cli.Command) (context.Context, error) {
+	detailed := c.Bool(CliDetailedExitCode)
+	for _, f := range c.Flags {
+		if rf, ok := f.(cli.RequiredFlag); ok && rf.IsRequired() && !c.IsSet(f.Names()[0]) {
+			return ctx, planError(detailed, fmt.Errorf("required flag %q not set", f.Names()[0]))
+		}
+	}
+	ctx, err := r.Before(ctx, c)
+	if err != nil {
+		return ctx, planError(detailed, err)
+	}
+	return ctx, nil
+}
+
+// planUsageError exits with the error exit code on invalid flags, the detailed-exitcode flag may be unparsed then
+func planUsageError(ctx context.Context, c *cli.Command, err error, isSubcommand bool) error {
+	return planError(true, err)
+}
+
+// planError makes sure errors never exit with the changes exit code (a go panic exits with 2)
+func planError(detailed bool, err error) error {
+	if !detailed {
+		return err
+	}
+	return cli.Exit(err.Error(), PlanExitCodeError)
+}
+
+func (r *Runner) planDashboards(dashboards []FolderDashboard, adopt bool) (PlanResult, error) {
+	var result PlanResult
+	for _, fd := range dashboards {
+		d := fd.Dashboard
+		folderUID := FolderUID(fd.Folder)
+		if d.Uid == nil {
+			return result, fmt.Errorf("dashboard %s has no uid, unable to compare with server", dashboardTitle(d))
+		}
+		change := ResourceChange{Kind: "dashboard", Uid: *d.Uid, Title: dashboardTitle(d)}
+		local, err := normalizeDashboard(d)
+		if err != nil {
+			return result, err
+		}
+		addOwnerTag(local, r.appName)
+		server, meta, found, err := r.getRemoteDashboard(*d.Uid)
+		if err != nil {
+			return result, err
+		}
+		if !found {
+			change.Action = PlanCreate
+			result.Changes = append(result.Changes, change)
+			continue
+		}
+		if err := r.checkOwner(*d.Uid, server, adopt); err != nil {
+			change.Action = PlanConflict
+			change.Diffs = append(change.Diffs, FieldDiff{Path: "owner", Before: dashboardOwner(server), After: r.appName})
+			result.Changes = append(result.Changes, change)
+			continue
+		}
+		delete(server, OwnerMetadataKey)
+		if meta != nil && meta.FolderUID != folderUID {
+			change.Diffs = append(change.Diffs, FieldDiff{Path: "meta.folderUid", Before: meta.FolderUID, After: folderUID})
+		}
+		change.Diffs = append(change.Diffs, diffValues("", server, local)...)
+		change.Action = PlanUnchanged
+		switch {
+		case dashboardOwner(server) == "":
+			change.Action = PlanAdopt
+		case len(change.Diffs) > 0:
+			change.Action = PlanUpdate
+		}
+		result.Changes = append(result.Changes, change)
+	}
+	return result, nil
+}
+
+func printPlan(result PlanResult) {
+	for _, c := range result.Changes {
+		switch c.Action {
+		case PlanCreate:
+			fmt.Printf("  + %s %q (%s) will be created\n", c.Kind, c.Title, c.Uid)
+		case PlanUpdate:
+			fmt.Printf("  ~ %s %q (%s) will be updated\n", c.Kind, c.Title, c.Uid)
+			for _, d := range c.Diffs {
+				fmt.Printf("      %s\n", d)
+			}
+		case PlanAdopt:
+			fmt.Printf("  & %s %q (%s) is not managed by any app and will be adopted\n", c.Kind, c.Title, c.Uid)
+			for _, d := range c.Diffs {
+				fmt.Printf("      %s\n", d)
+			}
+		case PlanConflict:
+			fmt.Printf("  ! %s %q (%s) is not managed by this app and will not be touched\n", c.Kind, c.Title, c.Uid)
+			for _, d := range c.Diffs {
+				fmt.Printf("      %s\n", d)
+			}
+		case PlanUnchanged:
+			fmt.Printf("    %s %q (%s) is up to date\n", c.Kind, c.Title, c.Uid)
+		}
+	}
+	fmt.Printf("\nPlan: %d to create, %d to update, %d to adopt, %d unchanged, %d conflicts.\n", result.Count(PlanCreate), result.Count(PlanUpdate), result.Count(PlanAdopt), result.Count(PlanUnchanged), result.Count(PlanConflict))
+}
+
+func dashboardTitle(d dashboard.Dashboard) string {
+	if d.Title == nil {
+		return ""
+	}
+	return *d.Title
+}
@@ -0,0 +1,112 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/grafana/grafana-foundation-sdk/go/cog"
+	"github.com/grafana/grafana-foundation-sdk/go/dashboard"
+	"github.com/urfave/cli/v3"
+)
+
+// planDashboard is the dashboard in code of the plan tests
+func planDashboard(t *testing.T) dashboard.Dashboard {
+	t.Helper()
+	d, err := dashboard.NewDashboardBuilder("Api").Uid("api").Tags([]string{"api"}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// remoteDashboard returns the answer of grafana for the dashboard, owned by owner if set
+func remoteDashboard(t *testing.T, d dashboard.Dashboard, owner string) map[string]any {
+	t.Helper()
+	body, err := normalizeDashboard(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner != "" {
+		addOwnerTag(body, owner)
+	}
+	return map[string]any{"dashboard": body, "meta": map[string]any{"folderUid": ""}}
+}
+
+// exitCode runs the cli and returns its exit code. Errors without exit code are counted like a main
+// panicking on them, which exits with 2 and would be read as pending changes.
+func exitCode(t *testing.T, app *cli.Command, args ...string) int {
+	t.Helper()
+	code := -1
+	app.ExitErrHandler = func(ctx context.Context, c *cli.Command, err error) {
+		var exitErr cli.ExitCoder
+		if errors.As(err, &exitErr) {
+			code = exitErr.ExitCode()
+		}
+	}
+	err := app.Run(context.Background(), append([]string{"test"}, args...))
+	switch {
+	case code >= 0:
+		return code
+	case err != nil:
+		return 2
+	}
+	return 0
+}
+
+func TestPlanDetailedExitCode(t *testing.T) {
+	d := planDashboard(t)
+	changed := planDashboard(t)
+	changed.Title = cog.ToPtr("Api v2")
+	tests := []struct {
+		name   string
+		remote func(r *http.Request) any
+		local  dashboard.Dashboard
+		args   []string
+		// noApikey leaves out the required --apikey
+		noApikey bool
+		want     int
+	}{
+		{name: "up to date", remote: func(r *http.Request) any { return remoteDashboard(t, d, "test") }, local: d, want: PlanExitCodeNoChanges},
+		{name: "create", remote: func(r *http.Request) any { return nil }, local: d, want: PlanExitCodeChanges},
+		{name: "update", remote: func(r *http.Request) any { return remoteDashboard(t, d, "test") }, local: changed, want: PlanExitCodeChanges},
+		{name: "adopt", remote: func(r *http.Request) any { return remoteDashboard(t, d, "") }, local: d, args: []string{"--adopt"}, want: PlanExitCodeChanges},
+		{name: "not managed without adopt", remote: func(r *http.Request) any { return remoteDashboard(t, d, "") }, local: d, want: PlanExitCodeError},
+		{name: "conflict", remote: func(r *http.Request) any { return remoteDashboard(t, d, "other") }, local: d, want: PlanExitCodeError},
+		{name: "grafana error", remote: func(r *http.Request) any { return "no dashboard json" }, local: d, want: PlanExitCodeError},
+		{name: "missing apikey", remote: func(r *http.Request) any { return nil }, local: d, noApikey: true, want: PlanExitCodeError},
+		{name: "unknown flag", remote: func(r *http.Request) any { return nil }, local: d, args: []string{"--unknown"}, want: PlanExitCodeError},
+		{name: "invalid server", remote: func(r *http.Request) any { return nil }, local: d, args: []string{"--server", "http://[::1"}, want: PlanExitCodeError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeGrafanaURL(t, tt.remote)
+			app, err := NewCli("test", DashboardBuilder(func(folderName string, c *cli.Command) ([]dashboard.Dashboard, error) {
+				return []dashboard.Dashboard{tt.local}, nil
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			args := []string{"dashboard", "plan", "--detailed-exitcode", "--server", server}
+			if !tt.noApikey {
+				args = append(args, "--apikey", "key")
+			}
+			if got := exitCode(t, app, append(args, tt.args...)...); got != tt.want {
+				t.Errorf("exit code = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanMissingApikeyWithDetailedExitCode(t *testing.T) {
+	app, err := NewCli("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, command := range []string{"dashboard", "datasource", "alert", "notification"} {
+		if got := exitCode(t, app, command, "plan", "--detailed-exitcode", "--server", "http://localhost:3000"); got != PlanExitCodeError {
+			t.Errorf("%s plan without --apikey exit code = %d, want %d", command, got, PlanExitCodeError)
+		}
+	}
+}
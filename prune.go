@@ -0,0 +1,110 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/grafana/grafana-foundation-sdk/go/cog"
+	"github.com/grafana/grafana-openapi-client-go/client/folders"
+	"github.com/grafana/grafana-openapi-client-go/client/search"
+	"github.com/grafana/grafana-openapi-client-go/models"
+)
+
+const searchPageSize int64 = 1000
+
+// listManagedDashboards returns all dashboards at the server which are managed by this cli.
+// If tag is set all dashboards with this tag are returned otherwise all dashboards inside the folders.
+func (r *Runner) listManagedDashboards(ctx context.Context, folderUIDs []string, tag string) (models.HitList, error) {
+	var res models.HitList
+	for page := int64(1); ; page++ {
+		params := search.NewSearchParamsWithContext(ctx).
+			WithType(cog.ToPtr("dash-db")).
+			WithLimit(cog.ToPtr(searchPageSize)).
+			WithPage(cog.ToPtr(page))
+		if tag != "" {
+			params = params.WithTag([]string{tag})
+		} else {
+			params = params.WithFolderUIDs(folderUIDs)
+		}
+		hits, err := r.client.Search.Search(params)
+		if err != nil {
+			return nil, fmt.Errorf("unable to search dashboards: %w", err)
+		}
+		res = append(res, hits.Payload...)
+		if int64(len(hits.Payload)) < searchPageSize {
+			return res, nil
+		}
+	}
+}
+
+// folderTree returns the uid of the folder and the uids of all its subfolders. The folder search of grafana is not recursive.
+func (r *Runner) folderTree(ctx context.Context, uid string) ([]string, error) {
+	res := []string{uid}
+	for i := 0; i < len(res); i++ {
+		parent := res[i]
+		for page := int64(1); ; page++ {
+			params := folders.NewGetFoldersParamsWithContext(ctx).
+				WithLimit(cog.ToPtr(searchPageSize)).
+				WithPage(cog.ToPtr(page))
+			if parent != "" {
+				params = params.WithParentUID(&parent)
+			}
+			list, err := r.client.Folders.GetFolders(params)
+			if err != nil {
+				return nil, fmt.Errorf("unable to list subfolders of %s: %w", parent, err)
+			}
+			for _, f := range list.Payload {
+				if !slices.Contains(res, f.UID) {
+					res = append(res, f.UID)
+				}
+			}
+			if int64(len(list.Payload)) < searchPageSize {
+				break
+			}
+		}
+	}
+	return res, nil
+}
+
+// prune deletes all dashboards owned by this app at the server which are not part of keep anymore
+// Without tag the root folder and all its subfolders are searched.
+func (r *Runner) prune(ctx context.Context, foldername, tag string, keep []FolderDashboard, dryRun bool) error {
+	known := make(map[string]bool, len(keep))
+	for _, d := range keep {
+		if d.Dashboard.Uid != nil {
+			known[*d.Dashboard.Uid] = true
+		}
+	}
+	var folderUIDs []string
+	if tag == "" {
+		var err error
+		folderUIDs, err = r.folderTree(ctx, FolderUID(foldername))
+		if err != nil {
+			return err
+		}
+	}
+	hits, err := r.listManagedDashboards(ctx, folderUIDs, tag)
+	if err != nil {
+		return err
+	}
+
+	errList := errors.Join(nil)
+	for _, h := range hits {
+		if known[h.UID] || !hitOwnedBy(h, r.appName) {
+			continue
+		}
+		if dryRun {
+			fmt.Printf("%s (%s): would be deleted\n", h.Title, h.UID)
+			continue
+		}
+		_, err := r.client.Dashboards.DeleteDashboardByUID(h.UID)
+		if err != nil {
+			errList = errors.Join(errList, fmt.Errorf("unable to delete dashboard %s: %w", h.UID, err))
+			continue
+		}
+		fmt.Printf("%s (%s): deleted\n", h.Title, h.UID)
+	}
+	return errList
+}
@@ -0,0 +1,229 @@
+package grafanasdkclistarter
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// Shapes of synthetic metrics
+const (
+	// ShapeSine is a gauge oscillating around Base by Amplitude within Period
+	ShapeSine = "sine"
+	// ShapeCounter is a counter increasing by Rate per second
+	ShapeCounter = "counter"
+	// ShapeHistogram observes Rate normal distributed values (Mean, StdDev) per second into Buckets
+	ShapeHistogram = "histogram"
+	// ShapeSpikes is a gauge at Base jumping to Base+Spike with Probability per minute
+	ShapeSpikes = "spikes"
+)
+
+// syntheticJob is the job of the synthetic exporter inside the dev prometheus config
+const syntheticJob = "synthetic"
+
+var metricNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// defaultBuckets are the default buckets of the prometheus client libraries
+var defaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// SyntheticMetric is a fake metric of the dev exporter. Every label set is a series, values are a function of the time.
+type SyntheticMetric struct {
+	Name  string `yaml:"name"`
+	Help  string `yaml:"help"`
+	Shape string `yaml:"shape"`
+	// Labels has a label set per series, without labels there is one series
+	Labels []map[string]string `yaml:"labels"`
+
+	Base      float64       `yaml:"base"`
+	Amplitude float64       `yaml:"amplitude"`
+	Period    time.Duration `yaml:"period"`
+
+	// Rate per second of counters and histogram observations
+	Rate float64 `yaml:"rate"`
+
+	Buckets []float64 `yaml:"buckets"`
+	Mean    float64   `yaml:"mean"`
+	StdDev  float64   `yaml:"stddev"`
+
+	Spike       float64 `yaml:"spike"`
+	Probability float64 `yaml:"probability"`
+}
+
+// syntheticFile is the yaml file of the synthetic-file flag
+type syntheticFile struct {
+	Metrics []SyntheticMetric `yaml:"metrics"`
+}
+
+// DevSyntheticMetrics adds metrics to the synthetic exporter of dev run
+func DevSyntheticMetrics(metrics ...SyntheticMetric) Option {
+	return func(runner *Runner, app *cli.Command) error {
+		runner.devSynthetic = append(runner.devSynthetic, metrics...)
+		return nil
+	}
+}
+
+// syntheticMetrics returns the validated metrics of the options and the yaml file
+func (r *Runner) syntheticMetrics(c *cli.Command) ([]SyntheticMetric, error) {
+	metrics := slices.Clone(r.devSynthetic)
+	if file := c.String(CliDevSyntheticFile); file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read synthetic metrics: %w", err)
+		}
+		var f syntheticFile
+		if err := yaml.Unmarshal(b, &f); err != nil {
+			return nil, fmt.Errorf("unable to parse synthetic metrics %s: %w", file, err)
+		}
+		metrics = append(metrics, f.Metrics...)
+	}
+	names := map[string]bool{}
+	for i, m := range metrics {
+		if !metricNamePattern.MatchString(m.Name) {
+			return nil, fmt.Errorf("synthetic metric %q has no valid name", m.Name)
+		}
+		if names[m.Name] {
+			return nil, fmt.Errorf("synthetic metric %s is declared twice", m.Name)
+		}
+		names[m.Name] = true
+		switch m.Shape {
+		case ShapeSine:
+			if m.Period <= 0 {
+				return nil, fmt.Errorf("synthetic metric %s needs a period", m.Name)
+			}
+		case ShapeCounter:
+		case ShapeHistogram:
+			if len(m.Buckets) == 0 {
+				metrics[i].Buckets = defaultBuckets
+			} else if !slices.IsSorted(m.Buckets) {
+				return nil, fmt.Errorf("buckets of synthetic metric %s have to be sorted", m.Name)
+			}
+			if m.StdDev <= 0 {
+				return nil, fmt.Errorf("synthetic metric %s needs a stddev", m.Name)
+			}
+		case ShapeSpikes:
+			if m.Probability < 0 || m.Probability > 1 {
+				return nil, fmt.Errorf("probability of synthetic metric %s has to be between 0 and 1", m.Name)
+			}
+		default:
+			return nil, fmt.Errorf("synthetic metric %s has the unknown shape %q", m.Name, m.Shape)
+		}
+		if m.Rate < 0 {
+			return nil, fmt.Errorf("rate of synthetic metric %s has to be positive", m.Name)
+		}
+	}
+	return metrics, nil
+}
+
+// syntheticScrapeTarget returns the scrape target of the exporter, if synthetic metrics are declared
+func (r *Runner) syntheticScrapeTarget(c *cli.Command) (*ScrapeTarget, error) {
+	metrics, err := r.syntheticMetrics(c)
+	if err != nil || len(metrics) == 0 {
+		return nil, err
+	}
+	return &ScrapeTarget{Job: syntheticJob, Ports: []int{int(c.Int(CliDevSyntheticPort))}, MetricsPath: "/metrics"}, nil
+}
+
+// startSyntheticExporter serves the metrics on all interfaces, so prometheus reaches it by the dev host address
+func startSyntheticExporter(metrics []SyntheticMetric, port int) (*http.Server, error) {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen for synthetic metrics: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(renderSyntheticMetrics(metrics, time.Now()))
+	})
+	srv := &http.Server{Handler: mux}
+	go func() {
+		if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			fmt.Printf("synthetic exporter stopped: %s\n", err)
+		}
+	}()
+	return srv, nil
+}
+
+// renderSyntheticMetrics writes the prometheus text format of all metrics at t
+func renderSyntheticMetrics(metrics []SyntheticMetric, t time.Time) []byte {
+	var buf bytes.Buffer
+	for _, m := range metrics {
+		metricType := "gauge"
+		switch m.Shape {
+		case ShapeCounter:
+			metricType = "counter"
+		case ShapeHistogram:
+			metricType = "histogram"
+		}
+		if m.Help != "" {
+			fmt.Fprintf(&buf, "# HELP %s %s\n", m.Name, m.Help)
+		}
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", m.Name, metricType)
+		labelSets := m.Labels
+		if len(labelSets) == 0 {
+			labelSets = []map[string]string{nil}
+		}
+		for i, labels := range labelSets {
+			if m.Shape == ShapeHistogram {
+				m.writeHistogram(&buf, labels, t)
+				continue
+			}
+			fmt.Fprintf(&buf, "%s%s %s\n", m.Name, openMetricsLabels(labels), formatSample(m.value(t, i, len(labelSets))))
+		}
+	}
+	return buf.Bytes()
+}
+
+// value of a gauge or counter series. The series get different phases and spikes.
+func (m SyntheticMetric) value(t time.Time, series, count int) float64 {
+	switch m.Shape {
+	case ShapeSine:
+		phase := float64(series) / float64(count)
+		x := float64(t.UnixMilli())/float64(m.Period.Milliseconds()) + phase
+		return m.Base + m.Amplitude*math.Sin(2*math.Pi*x)
+	case ShapeCounter:
+		return m.Rate * float64(t.UnixMilli()) / 1000
+	case ShapeSpikes:
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%s/%d/%d", m.Name, series, t.Unix()/60)
+		if float64(h.Sum64()%10000)/10000 < m.Probability {
+			return m.Base + m.Spike
+		}
+		return m.Base
+	}
+	return 0
+}
+
+func (m SyntheticMetric) writeHistogram(buf *bytes.Buffer, labels map[string]string, t time.Time) {
+	count := m.Rate * float64(t.UnixMilli()) / 1000
+	withLe := func(le string) string {
+		l := make(map[string]string, len(labels)+1)
+		for k, v := range labels {
+			l[k] = v
+		}
+		l["le"] = le
+		return openMetricsLabels(l)
+	}
+	for _, b := range m.Buckets {
+		cdf := 0.5 * (1 + math.Erf((b-m.Mean)/(m.StdDev*math.Sqrt2)))
+		fmt.Fprintf(buf, "%s_bucket%s %s\n", m.Name, withLe(strconv.FormatFloat(b, 'g', -1, 64)), formatSample(math.Floor(count*cdf)))
+	}
+	fmt.Fprintf(buf, "%s_bucket%s %s\n", m.Name, withLe("+Inf"), formatSample(math.Floor(count)))
+	fmt.Fprintf(buf, "%s_sum%s %s\n", m.Name, openMetricsLabels(labels), formatSample(math.Floor(count)*m.Mean))
+	fmt.Fprintf(buf, "%s_count%s %s\n", m.Name, openMetricsLabels(labels), formatSample(math.Floor(count)))
+}
+
+func formatSample(v float64) string {
+	return strings.ToLower(strconv.FormatFloat(v, 'f', -1, 64))
+}
@@ -0,0 +1,75 @@
+package grafanasdkclistarter
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSyntheticMetricValue(t *testing.T) {
+	at := time.UnixMilli(1700000040000)
+	tests := []struct {
+		name   string
+		metric SyntheticMetric
+		series int
+		count  int
+		want   float64
+	}{
+		{name: "counter", metric: SyntheticMetric{Shape: ShapeCounter, Rate: 2}, count: 1, want: 3400000080},
+		{name: "sine at zero", metric: SyntheticMetric{Shape: ShapeSine, Base: 10, Amplitude: 5, Period: time.Minute}, count: 1, want: 10},
+		{name: "sine second series is shifted", metric: SyntheticMetric{Shape: ShapeSine, Base: 10, Amplitude: 5, Period: time.Minute}, series: 1, count: 4, want: 15},
+		{name: "spikes never", metric: SyntheticMetric{Name: "s", Shape: ShapeSpikes, Base: 1, Spike: 9, Probability: 0}, count: 1, want: 1},
+		{name: "spikes always", metric: SyntheticMetric{Name: "s", Shape: ShapeSpikes, Base: 1, Spike: 9, Probability: 1}, count: 1, want: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.metric.value(at, tt.series, tt.count); math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("value() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderSyntheticMetrics(t *testing.T) {
+	at := time.Unix(100, 0)
+	tests := []struct {
+		name    string
+		metrics []SyntheticMetric
+		want    string
+	}{
+		{
+			name:    "counter with label sets",
+			metrics: []SyntheticMetric{{Name: "requests_total", Help: "all requests", Shape: ShapeCounter, Rate: 3, Labels: []map[string]string{{"path": "/a"}, {"path": "/b"}}}},
+			want: `# HELP requests_total all requests
+# TYPE requests_total counter
+requests_total{path="/a"} 300
+requests_total{path="/b"} 300
+`,
+		},
+		{
+			name:    "gauge without labels",
+			metrics: []SyntheticMetric{{Name: "queue", Shape: ShapeSpikes, Base: 4}},
+			want: `# TYPE queue gauge
+queue 4
+`,
+		},
+		{
+			name:    "histogram",
+			metrics: []SyntheticMetric{{Name: "duration_seconds", Shape: ShapeHistogram, Rate: 1, Mean: 1, StdDev: 0.5, Buckets: []float64{1, 2}, Labels: []map[string]string{{"path": "/a"}}}},
+			want: `# TYPE duration_seconds histogram
+duration_seconds_bucket{le="1",path="/a"} 50
+duration_seconds_bucket{le="2",path="/a"} 97
+duration_seconds_bucket{le="+Inf",path="/a"} 100
+duration_seconds_sum{path="/a"} 100
+duration_seconds_count{path="/a"} 100
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(renderSyntheticMetrics(tt.metrics, at)); got != tt.want {
+				t.Errorf("renderSyntheticMetrics() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
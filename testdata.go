@@ -0,0 +1,175 @@
+package grafanasdkclistarter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	goapi "github.com/grafana/grafana-openapi-client-go/client"
+	"github.com/grafana/grafana-openapi-client-go/models"
+	"github.com/urfave/cli/v3"
+)
+
+const (
+	// DevTestDataDatasourceUID is the uid of the testdata datasource of the dev stack
+	DevTestDataDatasourceUID = "testdata"
+	testDataType             = "grafana-testdata-datasource"
+	// mixedDatasourceUID lets every target of a panel use its own datasource
+	mixedDatasourceUID = "-- Mixed --"
+)
+
+// testDataScenarios are the scenarios of the testdata datasource a prometheus query can be swapped for
+var testDataScenarios = []string{
+	"random_walk",
+	"predictable_pulse",
+	"predictable_csv_wave",
+	"csv_metric_values",
+	"random_walk_table",
+	"exponential_heatmap_bucket_data",
+	"linear_heatmap_bucket_data",
+	"no_data_points",
+	"server_error_500",
+	"slow_query",
+}
+
+func addTestDataDatasource(client *goapi.GrafanaHTTPAPI) error {
+	err := upsertDevDatasource(client, &models.AddDataSourceCommand{
+		Name:   "TestData",
+		UID:    DevTestDataDatasourceUID,
+		Type:   testDataType,
+		Access: "proxy",
+	})
+	if err != nil {
+		return fmt.Errorf("error create testdata datasource at grafana: %w", err)
+	}
+	return nil
+}
+
+// DevTestData swaps every prometheus query for the testdata scenario while dashboards are applied by dev run, the dashboards in code stay untouched
+func DevTestData(scenario string) Option {
+	return func(runner *Runner, app *cli.Command) error {
+		if err := validateTestDataScenario(scenario); err != nil {
+			return err
+		}
+		return setDevRunDefault(app, CliDevTestDataScenario, scenario)
+	}
+}
+
+func validateTestDataScenario(scenario string) error {
+	if scenario != "" && !slices.Contains(testDataScenarios, scenario) {
+		return fmt.Errorf("unknown testdata scenario %s, use one of %s", scenario, strings.Join(testDataScenarios, ", "))
+	}
+	return nil
+}
+
+// testDataScenarioOf returns the validated scenario of the testdata-scenario flag, empty keeps the prometheus queries
+func testDataScenarioOf(c *cli.Command) (string, error) {
+	scenario := c.String(CliDevTestDataScenario)
+	if err := validateTestDataScenario(scenario); err != nil {
+		return "", fmt.Errorf("--%s: %w", CliDevTestDataScenario, err)
+	}
+	return scenario, nil
+}
+
+// swapTestDataQueries replaces the prometheus targets of all panels and rows of a normalized dashboard by the scenario
+// and the prometheus query variables by custom variables. Library panels keep their queries.
+func swapTestDataQueries(body map[string]any, scenario string) {
+	ref := map[string]any{"type": testDataType, "uid": DevTestDataDatasourceUID}
+	if templating, ok := body["templating"].(map[string]any); ok {
+		variables, _ := templating["list"].([]any)
+		for i, v := range variables {
+			if variable, ok := v.(map[string]any); ok && variable["type"] == "query" && isPrometheusRef(variable["datasource"]) {
+				variables[i] = testDataVariable(variable)
+			}
+		}
+	}
+	panels, _ := body["panels"].([]any)
+	for _, p := range panels {
+		panel, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+		if nested, ok := panel["panels"].([]any); ok {
+			swapTestDataQueries(map[string]any{"panels": nested}, scenario)
+		}
+		targets, ok := panel["targets"].([]any)
+		mixed := isMixedRef(panel["datasource"])
+		if !ok || (!mixed && !isPrometheusRef(panel["datasource"])) {
+			continue
+		}
+		swappedAny, keptAny := false, false
+		for i, t := range targets {
+			target, ok := t.(map[string]any)
+			if !ok {
+				continue
+			}
+			// targets without a datasource use the one of the panel
+			if ds := target["datasource"]; (ds == nil && mixed) || (ds != nil && !isPrometheusRef(ds)) {
+				keptAny = true
+				continue
+			}
+			swapped := map[string]any{"refId": target["refId"], "datasource": ref, "scenarioId": scenario}
+			// label templates of prometheus legends are unknown to testdata
+			if legend, ok := target["legendFormat"].(string); ok && legend != "" && legend != "__auto" && !strings.Contains(legend, "{{") {
+				swapped["alias"] = legend
+			}
+			if hide, ok := target["hide"]; ok {
+				swapped["hide"] = hide
+			}
+			targets[i] = swapped
+			swappedAny = true
+		}
+		switch {
+		case swappedAny && keptAny:
+			panel["datasource"] = map[string]any{"type": "datasource", "uid": mixedDatasourceUID}
+		case swappedAny:
+			panel["datasource"] = ref
+		}
+	}
+}
+
+// isMixedRef reports if the datasource ref of a panel is the mixed datasource
+func isMixedRef(v any) bool {
+	m, ok := v.(map[string]any)
+	return ok && m["uid"] == mixedDatasourceUID
+}
+
+// testDataVariable turns a prometheus query variable into a custom variable of its current values, the query would fail without prometheus
+func testDataVariable(variable map[string]any) map[string]any {
+	var values []string
+	current, _ := variable["current"].(map[string]any)
+	switch v := current["value"].(type) {
+	case string:
+		values = append(values, v)
+	case []any:
+		for _, value := range v {
+			if s, ok := value.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	values = slices.DeleteFunc(values, func(v string) bool { return v == "" || v == "$__all" })
+	if len(values) == 0 {
+		values = []string{DevTestDataDatasourceUID}
+	}
+	options := make([]any, 0, len(values))
+	query := make([]string, 0, len(values))
+	for i, v := range values {
+		options = append(options, map[string]any{"text": v, "value": v, "selected": i == 0})
+		// commas separate the values of a custom variable
+		query = append(query, strings.ReplaceAll(v, ",", `\,`))
+	}
+	res := map[string]any{
+		"type":    "custom",
+		"name":    variable["name"],
+		"query":   strings.Join(query, ","),
+		"options": options,
+		"current": map[string]any{"text": values[0], "value": values[0]},
+	}
+	for _, k := range []string{"label", "description", "hide", "multi", "includeAll", "allValue", "skipUrlSync"} {
+		if v, ok := variable[k]; ok {
+			res[k] = v
+		}
+	}
+	return res
+}
@@ -0,0 +1,78 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSwapTestDataQueries(t *testing.T) {
+	var body map[string]any
+	err := json.Unmarshal([]byte(`{
+	  "panels": [
+	    {"datasource": {"type": "prometheus", "uid": "prometheus"}, "targets": [{"refId": "A", "expr": "up", "legendFormat": "up"}]},
+	    {"datasource": {"type": "loki", "uid": "loki"}, "targets": [{"refId": "A", "expr": "{app=\"api\"}"}]},
+	    {"type": "row", "panels": [{"targets": [{"refId": "B", "expr": "rate(x[1m])", "legendFormat": "{{pod}}", "hide": true}]}]},
+	    {"datasource": {"type": "prometheus", "uid": "prometheus"}, "targets": [{"refId": "A", "expr": "up"}, {"refId": "B", "datasource": {"type": "loki", "uid": "loki"}, "expr": "{app=\"api\"}"}]},
+	    {"datasource": {"type": "datasource", "uid": "-- Mixed --"}, "targets": [{"refId": "A", "datasource": {"type": "prometheus", "uid": "prometheus"}, "expr": "up"}]}
+	  ],
+	  "templating": {"list": [
+	    {"type": "query", "name": "env", "label": "Env", "datasource": {"type": "prometheus", "uid": "prometheus"}, "query": "label_values(up, env)", "refresh": 1, "multi": true, "current": {"text": ["prod", "a,b"], "value": ["prod", "a,b"]}},
+	    {"type": "query", "name": "job", "query": "label_values(job)", "current": {"text": "All", "value": "$__all"}},
+	    {"type": "query", "name": "app", "datasource": {"type": "loki", "uid": "loki"}, "query": "label_values(app)"},
+	    {"type": "custom", "name": "quantile", "query": "0.5,0.9"}
+	  ]}
+	}`), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	swapTestDataQueries(body, "random_walk")
+
+	ref := map[string]any{"type": testDataType, "uid": DevTestDataDatasourceUID}
+	panels := body["panels"].([]any)
+	wantPanels := []any{
+		map[string]any{"datasource": ref, "targets": []any{map[string]any{"refId": "A", "datasource": ref, "scenarioId": "random_walk", "alias": "up"}}},
+		map[string]any{"datasource": map[string]any{"type": "loki", "uid": "loki"}, "targets": []any{map[string]any{"refId": "A", "expr": `{app="api"}`}}},
+		map[string]any{"type": "row", "panels": []any{map[string]any{"datasource": ref, "targets": []any{map[string]any{"refId": "B", "datasource": ref, "scenarioId": "random_walk", "hide": true}}}}},
+		map[string]any{"datasource": map[string]any{"type": "datasource", "uid": mixedDatasourceUID}, "targets": []any{
+			map[string]any{"refId": "A", "datasource": ref, "scenarioId": "random_walk"},
+			map[string]any{"refId": "B", "datasource": map[string]any{"type": "loki", "uid": "loki"}, "expr": `{app="api"}`},
+		}},
+		map[string]any{"datasource": ref, "targets": []any{map[string]any{"refId": "A", "datasource": ref, "scenarioId": "random_walk"}}},
+	}
+	if !reflect.DeepEqual(panels, wantPanels) {
+		t.Errorf("panels =\n%v\nwant\n%v", panels, wantPanels)
+	}
+
+	variables := body["templating"].(map[string]any)["list"].([]any)
+	wantVariables := []map[string]any{
+		{"type": "custom", "name": "env", "label": "Env", "multi": true, "query": `prod,a\,b`, "current": map[string]any{"text": "prod", "value": "prod"}},
+		{"type": "custom", "name": "job", "query": DevTestDataDatasourceUID, "current": map[string]any{"text": DevTestDataDatasourceUID, "value": DevTestDataDatasourceUID}},
+		{"type": "query", "name": "app", "query": "label_values(app)"},
+		{"type": "custom", "name": "quantile", "query": "0.5,0.9"},
+	}
+	for i, want := range wantVariables {
+		got := variables[i].(map[string]any)
+		for k, v := range want {
+			if !reflect.DeepEqual(got[k], v) {
+				t.Errorf("variable %s: %s = %v, want %v", want["name"], k, got[k], v)
+			}
+		}
+		if want["type"] == "custom" && (got["datasource"] != nil || got["refresh"] != nil) {
+			t.Errorf("variable %s keeps its prometheus query fields: %v", want["name"], got)
+		}
+	}
+}
+
+func TestDashboardApplyHasNoTestDataScenario(t *testing.T) {
+	app, err := NewCli("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.Run(context.Background(), []string{"test", "dashboard", "apply", "--server", "http://grafana.example.com", "--apikey", "key", "--" + CliDevTestDataScenario, "random_walk"})
+	if err == nil || !strings.Contains(err.Error(), "flag provided but not defined") {
+		t.Errorf("dashboard apply with --%s error = %v, want an undefined flag", CliDevTestDataScenario, err)
+	}
+}
@@ -0,0 +1,141 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// watchInterval is the poll interval of the go files in watch mode
+const watchInterval = time.Second
+
+// watchFiles returns the modification times of all go files and the go.mod/go.sum of the module at dir
+func watchFiles(dir string) (map[string]time.Time, error) {
+	res := map[string]time.Time{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != dir && (strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor" || d.Name() == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(p, ".go") && d.Name() != "go.mod" && d.Name() != "go.sum" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		res[p] = info.ModTime()
+		return nil
+	})
+	return res, err
+}
+
+// watchDashboards rebuilds the module at the current directory on every change of a go file and applies
+// the dashboards of the new binary to the dev grafana. afterBuild is called with the new binary.
+// Build and apply errors are printed, the dev stack keeps running.
+func watchDashboards(ctx context.Context, done <-chan os.Signal, grafanaUrl, apiKey string, applyArgs []string, afterBuild func(bin string) error) error {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp("", "grafana-dev-watch")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	bin := filepath.Join(tmp, "dashboards")
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+
+	last, err := watchFiles(pwd)
+	if err != nil {
+		return fmt.Errorf("unable to watch go files: %w", err)
+	}
+	fmt.Printf("Watching go files of %s\n", pwd)
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-ticker.C:
+		}
+		current, err := watchFiles(pwd)
+		if err != nil {
+			fmt.Printf("watch: %s\n", err)
+			continue
+		}
+		if maps.Equal(last, current) {
+			continue
+		}
+		last = current
+		fmt.Printf("[%s] change detected, rebuild\n", time.Now().Format(time.TimeOnly))
+		if err := buildModule(ctx, pwd, bin); err != nil {
+			fmt.Println(err)
+			continue
+		}
+		if err := applyWith(ctx, pwd, bin, grafanaUrl, apiKey, applyArgs); err != nil {
+			fmt.Println(err)
+		}
+		if err := afterBuild(bin); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+func buildModule(ctx context.Context, dir, bin string) error {
+	build := exec.CommandContext(ctx, "go", "build", "-o", bin, ".")
+	build.Dir = dir
+	out, err := build.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("build failed:\n%s", out)
+	}
+	return nil
+}
+
+// devApplyArgs returns the apply flags of dev run for the rebuilt app, so every watch apply lands like the startup apply.
+// A testdata scenario swaps the prometheus queries.
+func devApplyArgs(c *cli.Command, scenario string) []string {
+	var args []string
+	if folder := c.String(CliFolderName); folder != "" {
+		args = append(args, "--"+CliFolderName, folder)
+	}
+	if c.Bool(CliPrune) {
+		args = append(args, "--"+CliPrune)
+		if tag := c.String(CliPruneTag); tag != "" {
+			args = append(args, "--"+CliPruneTag, tag)
+		}
+	}
+	if scenario != "" {
+		args = append(args, "--"+CliDevTestDataScenario, scenario)
+	}
+	return args
+}
+
+// applyWith applies the dashboards of the app at bin to the dev grafana
+func applyWith(ctx context.Context, dir, bin, grafanaUrl, apiKey string, applyArgs []string) error {
+	args := append([]string{"dev", "apply", "--" + CliServer, grafanaUrl, "--" + CliApiKey, apiKey}, applyArgs...)
+	apply := exec.CommandContext(ctx, bin, args...)
+	apply.Dir = dir
+	apply.Stdout = os.Stdout
+	apply.Stderr = os.Stderr
+	if err := apply.Run(); err != nil {
+		return fmt.Errorf("apply failed: %w", err)
+	}
+	return nil
+}
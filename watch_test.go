@@ -0,0 +1,66 @@
+package grafanasdkclistarter
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/urfave/cli/v3"
+)
+
+// subCommand returns the command of the path below cmd
+func subCommand(t *testing.T, cmd *cli.Command, path ...string) *cli.Command {
+	t.Helper()
+	for _, name := range path {
+		next := cmd.Command(name)
+		if next == nil {
+			t.Fatalf("command %s has no sub command %s", cmd.Name, name)
+		}
+		cmd = next
+	}
+	return cmd
+}
+
+func TestDevApplyArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{name: "default folder", args: nil, want: nil},
+		{name: "folder", args: []string{"--foldername", "Platform/Api"}, want: []string{"--foldername", "Platform/Api"}},
+		{name: "prune with tag", args: []string{"--prune", "--prune-tag", "api"}, want: []string{"--prune", "--prune-tag", "api"}},
+		{name: "prune tag without prune", args: []string{"--prune-tag", "api"}, want: nil},
+		{name: "scenario", args: []string{"--foldername", "Api", "--testdata-scenario", "random_walk"}, want: []string{"--foldername", "Api", "--testdata-scenario", "random_walk"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, err := NewCli("test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			subCommand(t, app, "dev", "run").Action = func(ctx context.Context, c *cli.Command) error {
+				scenario, err := testDataScenarioOf(c)
+				if err != nil {
+					return err
+				}
+				got = devApplyArgs(c, scenario)
+				return nil
+			}
+			if err := app.Run(context.Background(), append([]string{"test", "dev", "run", "--datasource", "prometheus"}, tt.args...)); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("devApplyArgs() = %q, want %q", got, tt.want)
+			}
+			// the rebuilt app has to accept the forwarded flags
+			apply := subCommand(t, app, "dev", "apply")
+			apply.Before = nil
+			apply.Action = func(ctx context.Context, c *cli.Command) error { return nil }
+			if err := app.Run(context.Background(), append([]string{"test", "dev", "apply", "--server", "http://localhost:3000", "--apikey", "key"}, got...)); err != nil {
+				t.Errorf("dev apply %q: %s", got, err)
+			}
+		})
+	}
+}